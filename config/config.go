@@ -9,8 +9,10 @@ import (
 
 type (
 	Config struct {
-		HTTP HTTP `yaml:"http"`
-		DB   DB   `yaml:"db"`
+		HTTP          HTTP          `yaml:"http"`
+		DB            DB            `yaml:"db"`
+		Workers       Workers       `yaml:"workers"`
+		Notifications Notifications `yaml:"notifications"`
 	}
 	HTTP struct {
 		Address     string        `yaml:"address"`
@@ -28,6 +30,18 @@ type (
 		ConnAttempts int           `yaml:"conn-attempts"`
 		ConnTimeout  time.Duration `yaml:"conn-timeout"`
 	}
+	Workers struct {
+		RolloutInterval      time.Duration `yaml:"rollout-interval" env-default:"1m"`
+		ExpiryInterval       time.Duration `yaml:"expiry-interval" env-default:"1m"`
+		ExpiryBatchSize      int           `yaml:"expiry-batch-size" env-default:"1000"`
+		NotificationInterval time.Duration `yaml:"notification-interval" env-default:"5m"`
+	}
+	Notifications struct {
+		Window         time.Duration `yaml:"window" env-default:"24h"`
+		Notifier       string        `yaml:"notifier" env-default:"log"` // log or webhook
+		WebhookURL     string        `yaml:"webhook-url" env:"NOTIFICATIONS_WEBHOOK_URL"`
+		WebhookTimeout time.Duration `yaml:"webhook-timeout" env-default:"5s"`
+	}
 )
 
 const (
@@ -10,8 +10,10 @@ import (
 	"experiment.io/config"
 	"experiment.io/internal/controller/http"
 	"experiment.io/internal/controller/http/handlers/middleware"
+	"experiment.io/internal/notifier"
 	repo "experiment.io/internal/repo/pg"
 	"experiment.io/internal/usecase"
+	"experiment.io/internal/worker"
 	"experiment.io/pkg/hasher"
 	logger "experiment.io/pkg/logger"
 	ginLogger "experiment.io/pkg/logger/gin-logger"
@@ -33,6 +35,7 @@ func Run(ctx context.Context, cfg *config.Config) {
 
 	// Repository
 	segmentRepo := repo.NewSegmentRepository(pg)
+	experimentRepo := repo.NewExperimentRepository(pg)
 
 	dirToStorageCSV := "./history"
 	userRepo, err := repo.NewUserRepository(pg, dirToStorageCSV)
@@ -40,21 +43,39 @@ func Run(ctx context.Context, cfg *config.Config) {
 		log.Fatal("unable to create user repository")
 	}
 
+	l := logger.New()
+
 	// Usecase
 	segmentUC := usecase.NewSegmentUsecase(segmentRepo)
-	userUC := usecase.NewUserUsecase(userRepo)
+	userUC := usecase.NewUserUsecase(userRepo, l)
+	experimentUC := usecase.NewExperimentUsecase(experimentRepo)
+	expiryUC := usecase.NewExpiryUsecase(userRepo, cfg.Workers.ExpiryBatchSize)
 
 	secretKey := cfg.HTTP.JWTSecret
 	hasher := hasher.New()
 	authUC := usecase.NewAuthUsecase(userRepo, hasher, secretKey)
 
+	// Expiry notifications
+	var expiryNotifier usecase.Notifier
+	switch cfg.Notifications.Notifier {
+	case "log":
+		expiryNotifier = notifier.NewLog(l)
+	case "webhook":
+		if cfg.Notifications.WebhookURL == "" {
+			log.Fatal("notifications webhook-url is required for the webhook notifier")
+		}
+		expiryNotifier = notifier.NewWebhook(cfg.Notifications.WebhookURL, cfg.Notifications.WebhookTimeout)
+	default:
+		log.Fatalf("unknown notifier %q", cfg.Notifications.Notifier)
+	}
+	notificationUC := usecase.NewNotificationUsecase(userRepo, expiryNotifier, cfg.Notifications.Window)
+
 	// Create http server
-	l := logger.New()
 	g := gin.New()
 	g.Use(gin.Recovery())
 	g.Use(ginLogger.LoggingMiddleware(l))
 
-	http.SetupRouter(g, l, segmentUC, userUC, authUC, middleware.Authorized(secretKey))
+	http.SetupRouter(g, l, segmentUC, userUC, authUC, experimentUC, expiryUC, middleware.Authorized(secretKey))
 	srv, err := http.NewServer(g, cfg.HTTP)
 	if err != nil {
 		log.Fatal(err)
@@ -67,6 +88,13 @@ func Run(ctx context.Context, cfg *config.Config) {
 		}
 	}()
 
+	// Background workers
+	rolloutScheduler := worker.NewRolloutScheduler(segmentUC, l, cfg.Workers.RolloutInterval)
+	go rolloutScheduler.Run(ctx)
+	expiryReaper := worker.NewExpiryReaper(expiryUC, l, cfg.Workers.ExpiryInterval)
+	go expiryReaper.Run(ctx)
+	notificationScheduler := worker.NewNotificationScheduler(notificationUC, l, cfg.Workers.NotificationInterval)
+	go notificationScheduler.Run(ctx)
 
 	// Graceful shutdown
 	<-ctx.Done()
@@ -33,12 +33,18 @@ type requestUser struct {
 	Pass string `json:"pass" binding:"required,max=50"`
 }
 
+// attributes are used by segment targeting rules
+type requestRegistration struct {
+	requestUser
+	Attributes map[string]any `json:"attributes"`
+}
+
 type responseRegistration struct {
 	ID int `json:"id"`
 }
 
 func (h *authHandler) registration(c *gin.Context) {
-	var req requestUser
+	var req requestRegistration
 	if err := c.BindJSON(&req); err != nil {
 		h.l.Error(err)
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": err.Error()})
@@ -46,8 +52,9 @@ func (h *authHandler) registration(c *gin.Context) {
 	}
 
 	id, err := h.uc.Registration(entity.User{
-		Name:     req.Name,
-		Password: req.Pass,
+		Name:       req.Name,
+		Password:   req.Pass,
+		Attributes: req.Attributes,
 	})
 	if err != nil {
 		h.l.Error(err)
@@ -28,6 +28,12 @@ func TestRegistration(t *testing.T) {
 			errUsecase:     nil,
 			expectedStatus: http.StatusCreated,
 		},
+		{
+			name:           "Success with attributes",
+			reqJSON:        `{"name": "testuser", "pass": "testpass", "attributes": {"country": "RU", "platform": "ios"}}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusCreated,
+		},
 		{
 			name:           "Invalid pass",
 			reqJSON:        `{"name": "testuser", "pass": "testpass"}`,
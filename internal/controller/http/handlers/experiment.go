@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"experiment.io/internal/entity"
+	"experiment.io/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type experimentHandler struct {
+	uc ExperimentUsecase
+	l  *logger.Logger
+}
+
+type ExperimentUsecase interface {
+	NewExperiment(exp entity.Experiment) (map[string][]int, error)
+	Experiment(slug string) (entity.Experiment, error)
+}
+
+func NewExperimentHandler(route *gin.RouterGroup, l *logger.Logger, uc ExperimentUsecase) {
+	h := &experimentHandler{uc, l}
+
+	{
+		route.POST("/experiments", h.newExperiment)
+		route.GET("/experiments/:slug", h.experiment)
+	}
+}
+
+// variant segments are named <slug>_<variant name>, so both parts are limited
+type requestNewExperiment struct {
+	Slug     string           `json:"slug" binding:"required,max=50"`
+	Salt     string           `json:"salt" binding:"max=100"`
+	Layer    string           `json:"layer" binding:"max=100"`
+	Variants []requestVariant `json:"variants" binding:"required,min=2,max=20,dive"`
+}
+
+type requestVariant struct {
+	Name   string `json:"name" binding:"required,max=49"`
+	Weight int    `json:"weight" binding:"required,min=1,max=10000"`
+}
+
+type responseNewExperiment struct {
+	Variants map[string][]int `json:"variants"`
+}
+
+func (h *experimentHandler) newExperiment(c *gin.Context) {
+	var req requestNewExperiment
+	if err := c.BindJSON(&req); err != nil {
+		h.l.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": err.Error()})
+		return
+	}
+
+	variants := make([]entity.Variant, len(req.Variants))
+	for i, v := range req.Variants {
+		variants[i] = entity.Variant{
+			Name:   v.Name,
+			Weight: v.Weight,
+		}
+	}
+
+	assigned, err := h.uc.NewExperiment(entity.Experiment{
+		Slug:     req.Slug,
+		Salt:     req.Salt,
+		Layer:    req.Layer,
+		Variants: variants,
+	})
+	if err != nil {
+		h.l.Error(err)
+		switch {
+		case errors.Is(err, entity.ErrInvalidExperiment):
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrInvalidExperiment.Error()})
+			return
+		case errors.Is(err, entity.ErrExperimentAlreadyExist):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"msg:": entity.ErrExperimentAlreadyExist.Error()})
+			return
+		case errors.Is(err, entity.ErrSegmentAlreadyExist):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"msg:": entity.ErrSegmentAlreadyExist.Error()})
+			return
+		default:
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, responseNewExperiment{
+		Variants: assigned,
+	})
+}
+
+type responseExperiment struct {
+	Slug     string            `json:"slug"`
+	Salt     string            `json:"salt"`
+	Layer    string            `json:"layer,omitempty"`
+	Variants []responseVariant `json:"variants"`
+}
+
+type responseVariant struct {
+	Name    string `json:"name"`
+	Weight  int    `json:"weight"`
+	Segment string `json:"segment"`
+	Members int    `json:"members"`
+}
+
+func (h *experimentHandler) experiment(c *gin.Context) {
+	slug := c.Param("slug")
+
+	exp, err := h.uc.Experiment(slug)
+	if err != nil {
+		h.l.Error(err)
+		if errors.Is(err, entity.ErrExperimentNotFound) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	resp := responseExperiment{
+		Slug:     exp.Slug,
+		Salt:     exp.Salt,
+		Layer:    exp.Layer,
+		Variants: make([]responseVariant, len(exp.Variants)),
+	}
+	for i, v := range exp.Variants {
+		resp.Variants[i] = responseVariant{
+			Name:    v.Name,
+			Weight:  v.Weight,
+			Segment: v.Segment,
+			Members: v.Members,
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"experiment.io/internal/entity"
+	"experiment.io/internal/mocks"
+	"experiment.io/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewExperiment(t *testing.T) {
+	validJSON := `{"slug": "CHECKOUT", "variants": [{"name": "control", "weight": 50}, {"name": "treatment", "weight": 50}]}`
+
+	testCases := []struct {
+		name           string
+		reqJSON        string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			reqJSON:        validJSON,
+			errUsecase:     nil,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Experiment already exists",
+			reqJSON:        validJSON,
+			errUsecase:     entity.ErrExperimentAlreadyExist,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Variant segment already exists",
+			reqJSON:        validJSON,
+			errUsecase:     entity.ErrSegmentAlreadyExist,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Invalid experiment",
+			reqJSON:        validJSON,
+			errUsecase:     entity.ErrInvalidExperiment,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unexpected usecase error",
+			reqJSON:        validJSON,
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "Single variant",
+			reqJSON:        `{"slug": "CHECKOUT", "variants": [{"name": "control", "weight": 50}]}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Negative weight",
+			reqJSON:        `{"slug": "CHECKOUT", "variants": [{"name": "control", "weight": 50}, {"name": "treatment", "weight": -1}]}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.ExperimentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := experimentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("NewExperiment", mock.Anything).Return(map[string][]int{"control": {1}}, tc.errUsecase)
+
+		mockContext.Request = httptest.NewRequest("POST", "/experiments", strings.NewReader(tc.reqJSON))
+		mockContext.Request.Header.Set("Accept", "application/json")
+
+		handler.newExperiment(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
+func TestExperiment(t *testing.T) {
+	testCases := []struct {
+		name           string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Non-existent experiment",
+			errUsecase:     entity.ErrExperimentNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Unexpected usecase error",
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.ExperimentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := experimentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("Experiment", mock.Anything).Return(entity.Experiment{
+			Slug:     "CHECKOUT",
+			Variants: []entity.Variant{{Name: "control", Weight: 50, Segment: "CHECKOUT_control"}},
+		}, tc.errUsecase)
+
+		mockContext.Params = []gin.Param{{Key: "slug", Value: "CHECKOUT"}}
+		mockContext.Request = httptest.NewRequest("GET", "/experiments/CHECKOUT", nil)
+		mockContext.Request.Header.Set("Accept", "application/json")
+
+		handler.experiment(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"experiment.io/internal/entity"
+	"experiment.io/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+type expiryHandler struct {
+	uc ExpiryUsecase
+	l  *logger.Logger
+}
+
+type ExpiryUsecase interface {
+	LastExpiryRun() entity.ExpiryRun
+}
+
+func NewExpiryAdminHandler(route *gin.RouterGroup, l *logger.Logger, uc ExpiryUsecase) {
+	h := &expiryHandler{uc, l}
+
+	{
+		route.GET("/expiry/status", h.expiryStatus)
+	}
+}
+
+// times are omitted until the first run of the expiry worker
+type responseExpiryStatus struct {
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	Removed      int        `json:"removed"`
+	TotalRemoved int        `json:"total_removed"`
+	Error        string     `json:"error,omitempty"`
+}
+
+func (h *expiryHandler) expiryStatus(c *gin.Context) {
+	run := h.uc.LastExpiryRun()
+
+	var resp responseExpiryStatus
+	if !run.StartedAt.IsZero() {
+		resp.StartedAt = &run.StartedAt
+		resp.FinishedAt = &run.FinishedAt
+	}
+	resp.Removed = run.Removed
+	resp.TotalRemoved = run.TotalRemoved
+	if run.Err != nil {
+		resp.Error = run.Err.Error()
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"experiment.io/internal/entity"
+	"experiment.io/internal/mocks"
+	"experiment.io/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiryStatus(t *testing.T) {
+	startedAt := time.Date(2023, 9, 26, 12, 0, 0, 0, time.UTC)
+	finishedAt := startedAt.Add(time.Second)
+
+	testCase := []struct {
+		name         string
+		run          entity.ExpiryRun
+		expectedResp responseExpiryStatus
+	}{
+		{
+			name:         "Before the first run",
+			run:          entity.ExpiryRun{},
+			expectedResp: responseExpiryStatus{},
+		},
+		{
+			name: "Successful run",
+			run: entity.ExpiryRun{
+				StartedAt:    startedAt,
+				FinishedAt:   finishedAt,
+				Removed:      3,
+				TotalRemoved: 10,
+			},
+			expectedResp: responseExpiryStatus{
+				StartedAt:    &startedAt,
+				FinishedAt:   &finishedAt,
+				Removed:      3,
+				TotalRemoved: 10,
+			},
+		},
+		{
+			name: "Failed run",
+			run: entity.ExpiryRun{
+				StartedAt:  startedAt,
+				FinishedAt: startedAt,
+				Err:        entity.ErrInternalServer,
+			},
+			expectedResp: responseExpiryStatus{
+				StartedAt:  &startedAt,
+				FinishedAt: &startedAt,
+				Error:      entity.ErrInternalServer.Error(),
+			},
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUsecase := new(mocks.ExpiryUsecase)
+			w := httptest.NewRecorder()
+			mockContext, _ := gin.CreateTestContext(w)
+
+			handler := expiryHandler{
+				uc: mockUsecase,
+				l:  logger.New(),
+			}
+			mockUsecase.On("LastExpiryRun").Return(tc.run)
+
+			mockContext.Request = httptest.NewRequest("GET", "/admin/expiry/status", nil)
+
+			handler.expiryStatus(mockContext)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var resp responseExpiryStatus
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Equal(t, tc.expectedResp.Removed, resp.Removed)
+			require.Equal(t, tc.expectedResp.TotalRemoved, resp.TotalRemoved)
+			require.Equal(t, tc.expectedResp.Error, resp.Error)
+			if tc.expectedResp.StartedAt == nil {
+				require.Nil(t, resp.StartedAt)
+				require.Nil(t, resp.FinishedAt)
+			} else {
+				require.True(t, tc.expectedResp.StartedAt.Equal(*resp.StartedAt))
+				require.True(t, tc.expectedResp.FinishedAt.Equal(*resp.FinishedAt))
+			}
+		})
+	}
+}
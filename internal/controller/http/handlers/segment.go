@@ -3,6 +3,7 @@ package handlers
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"experiment.io/internal/entity"
 	"experiment.io/pkg/logger"
@@ -16,21 +17,85 @@ type segmentHandler struct {
 type SegmentUsecase interface {
 	NewSegment(seg entity.Segment) error
 	NewSegmentWithAutoAssign(seg entity.Segment, percentAssigned int) ([]int, error)
-	DeleteSegment(slug string) error
+	CloneSegment(slug string, clone entity.SegmentClone) ([]int, error)
+	NewSegmentFromExpression(seg entity.Segment, expr entity.SegmentExpression) ([]int, error)
+	ArchiveSegment(slug string) error
+	RestoreSegment(slug string) error
+	PurgeSegment(slug string) error
+	AutoAssignSegment(slug string, percent int, expiresAt *time.Time) ([]int, error)
+	SetRollout(slug string, percent int) (entity.RolloutChange, error)
+	SetRolloutSchedule(slug string, steps []entity.RolloutStep) ([]entity.RolloutStep, error)
+	RolloutSchedule(slug string) ([]entity.RolloutStep, error)
+	BackfillRollouts() (int, error)
+	SetHoldout(h entity.Holdout) (entity.Holdout, error)
+	Holdout() (entity.Holdout, error)
+	Segments(filter entity.SegmentFilter) ([]entity.SegmentInfo, int, error)
+	Segment(slug string) (entity.SegmentInfo, error)
+	UpdateSegment(slug string, upd entity.SegmentUpdate) (entity.SegmentInfo, error)
+	SegmentChanges(slug string) ([]entity.SegmentChange, error)
 }
 
 func NewSegmentHandler(route *gin.RouterGroup, l *logger.Logger, uc SegmentUsecase) {
 	h := &segmentHandler{uc, l}
 
 	{
-		route.DELETE("/segments/:slug", h.deleteSegment)
+		route.GET("/segments", h.segments)
+		route.GET("/segments/:slug", h.segment)
+		route.PATCH("/segments/:slug", h.updateSegment)
+		route.GET("/segments/:slug/changes", h.segmentChanges)
+		route.DELETE("/segments/:slug", h.archiveSegment)
+		route.POST("/segments/:slug/restore", h.restoreSegment)
+		route.POST("/segments/:slug/clone", h.cloneSegment)
+		route.POST("/segments/:slug/auto-assign", h.autoAssignSegment)
+		route.PATCH("/segments/:slug/rollout", h.setRollout)
+		route.PUT("/segments/:slug/rollout/schedule", h.setRolloutSchedule)
+		route.GET("/segments/:slug/rollout/schedule", h.rolloutSchedule)
 		route.POST("/segments", h.newSegment)
 		route.POST("/segments/auto-assign", h.newSegmentWithAutoAssign)
+		route.POST("/segments/compose", h.newSegmentFromExpression)
+		route.POST("/segments/rollouts/backfill", h.backfillRollouts)
+		route.PUT("/holdout", h.setHoldout)
+		route.GET("/holdout", h.holdout)
+	}
+}
+
+// Registers the segment operations that can not be undone
+func NewSegmentAdminHandler(route *gin.RouterGroup, l *logger.Logger, uc SegmentUsecase) {
+	h := &segmentHandler{uc, l}
+
+	{
+		route.DELETE("/segments/:slug", h.purgeSegment)
 	}
 }
 
 type requestNewSegment struct {
-	Slug string `json:"slug" binding:"required,max=100"`
+	Slug  string `json:"slug" binding:"required,max=100"`
+	Layer string `json:"layer" binding:"max=100"`
+	Rule  string `json:"rule" binding:"max=1000"`
+	requestSegmentMetadata
+	requestSegmentWindow
+	requestSegmentPrerequisites
+}
+
+// users can be assigned the segment only while they are members of all its prerequisites
+type requestSegmentPrerequisites struct {
+	Prerequisites []string `json:"prerequisites" binding:"omitempty,max=20,dive,required,max=100"`
+	// what happens to the membership when the user loses a prerequisite
+	RemovalPolicy string `json:"removal_policy" binding:"omitempty,oneof=block cascade"`
+}
+
+type requestSegmentWindow struct {
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+type requestSegmentMetadata struct {
+	Description  string   `json:"description" binding:"max=1000"`
+	Owner        string   `json:"owner" binding:"max=100"`
+	Tags         []string `json:"tags" binding:"max=20,dive,required,max=50"`
+	AllowHoldout bool     `json:"allow_holdout"`
+	// maximum number of active members, the segment is unlimited when omitted
+	MaxMembers *int `json:"max_members" binding:"omitempty,min=1"`
 }
 
 func (h *segmentHandler) newSegment(c *gin.Context) {
@@ -42,13 +107,44 @@ func (h *segmentHandler) newSegment(c *gin.Context) {
 	}
 
 	if err := h.uc.NewSegment(entity.Segment{
-		Slug: req.Slug,
+		Slug:          req.Slug,
+		Layer:         req.Layer,
+		Rule:          req.Rule,
+		Description:   req.Description,
+		Owner:         req.Owner,
+		Tags:          req.Tags,
+		StartsAt:      req.StartsAt,
+		EndsAt:        req.EndsAt,
+		AllowHoldout:  req.AllowHoldout,
+		Prerequisites: req.Prerequisites,
+		RemovalPolicy: entity.RemovalPolicy(req.RemovalPolicy),
+		MaxMembers:    req.MaxMembers,
 	}); err != nil {
 		h.l.Error(err)
 		if errors.Is(err, entity.ErrSegmentAlreadyExist) {
 			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"msg:": entity.ErrSegmentAlreadyExist.Error()})
 			return
 		}
+		if errors.Is(err, entity.ErrInvalidRule) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": err.Error()})
+			return
+		}
+		if errors.Is(err, entity.ErrInvalidSegmentWindow) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrInvalidSegmentWindow.Error()})
+			return
+		}
+		if errors.Is(err, entity.ErrPrerequisiteCycle) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrPrerequisiteCycle.Error()})
+			return
+		}
+		if errors.Is(err, entity.ErrInvalidMaxMembers) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrInvalidMaxMembers.Error()})
+			return
+		}
+		if errors.Is(err, entity.ErrPrerequisiteNotFound) {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"msg:": entity.ErrPrerequisiteNotFound.Error()})
+			return
+		}
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
@@ -56,15 +152,55 @@ func (h *segmentHandler) newSegment(c *gin.Context) {
 	c.Status(http.StatusCreated)
 }
 
-func (h *segmentHandler) deleteSegment(c *gin.Context) {
+func (h *segmentHandler) archiveSegment(c *gin.Context) {
+	slug := c.Param("slug")
+
+	if err := h.uc.ArchiveSegment(slug); err != nil {
+		h.l.Error(err)
+		if errors.Is(err, entity.ErrSegmentNotFound) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *segmentHandler) restoreSegment(c *gin.Context) {
+	slug := c.Param("slug")
+
+	if err := h.uc.RestoreSegment(slug); err != nil {
+		h.l.Error(err)
+		if errors.Is(err, entity.ErrSegmentNotFound) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, entity.ErrSegmentNotArchived) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"msg:": entity.ErrSegmentNotArchived.Error()})
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *segmentHandler) purgeSegment(c *gin.Context) {
 	slug := c.Param("slug")
 
-	if err := h.uc.DeleteSegment(slug); err != nil {
+	if err := h.uc.PurgeSegment(slug); err != nil {
 		h.l.Error(err)
 		if errors.Is(err, entity.ErrSegmentNotFound) {
 			c.AbortWithStatus(http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, entity.ErrSegmentNotArchived) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"msg:": entity.ErrSegmentNotArchived.Error()})
+			return
+		}
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
@@ -72,9 +208,183 @@ func (h *segmentHandler) deleteSegment(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+// ttl or an absolute expires_at, the added memberships never expire when both are omitted
+type requestExpiration struct {
+	TTL       *TTL       `json:"ttl"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (r requestExpiration) expiration() *time.Time {
+	if r.TTL != nil {
+		expiredDate := time.Now().Add(time.Duration(*r.TTL))
+		return &expiredDate
+	}
+	return r.ExpiresAt
+}
+
+type requestAutoAssignSegment struct {
+	Percent int `json:"percent" binding:"required,numeric,min=0,max=100"`
+	requestExpiration
+}
+
+func (h *segmentHandler) autoAssignSegment(c *gin.Context) {
+	slug := c.Param("slug")
+
+	var req requestAutoAssignSegment
+	if err := c.BindJSON(&req); err != nil {
+		h.l.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": err.Error()})
+		return
+	}
+
+	if !NewValidator().checkExpirationIsValid(req.TTL, req.ExpiresAt) {
+		h.l.Error(entity.ErrInvalidExpiration)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrInvalidExpiration.Error()})
+		return
+	}
+
+	ids, err := h.uc.AutoAssignSegment(slug, req.Percent, req.expiration())
+	if err != nil {
+		h.l.Error(err)
+		if errors.Is(err, entity.ErrSegmentNotFound) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, entity.ErrSegmentArchived) {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"msg:": entity.ErrSegmentArchived.Error()})
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, responseNewSegmentWithAutoAssign{
+		IDS: ids,
+	})
+}
+
+// either a segment slug or an operation (union, intersect, difference) over at least two arguments
+type requestSegmentExpression struct {
+	Slug string                     `json:"slug"`
+	Op   string                     `json:"op"`
+	Args []requestSegmentExpression `json:"args"`
+}
+
+func (e requestSegmentExpression) toEntity() entity.SegmentExpression {
+	expr := entity.SegmentExpression{
+		Slug: e.Slug,
+		Op:   entity.SetOperation(e.Op),
+	}
+	for _, arg := range e.Args {
+		expr.Args = append(expr.Args, arg.toEntity())
+	}
+	return expr
+}
+
+type requestNewSegmentFromExpression struct {
+	Slug         string                   `json:"slug" binding:"required,max=100"`
+	Expression   requestSegmentExpression `json:"expression"`
+	Description  string                   `json:"description" binding:"max=1000"`
+	Owner        string                   `json:"owner" binding:"max=100"`
+	Tags         []string                 `json:"tags" binding:"max=20,dive,required,max=50"`
+	AllowHoldout bool                     `json:"allow_holdout"`
+}
+
+func (h *segmentHandler) newSegmentFromExpression(c *gin.Context) {
+	var req requestNewSegmentFromExpression
+	if err := c.BindJSON(&req); err != nil {
+		h.l.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": err.Error()})
+		return
+	}
+
+	ids, err := h.uc.NewSegmentFromExpression(entity.Segment{
+		Slug:         req.Slug,
+		Description:  req.Description,
+		Owner:        req.Owner,
+		Tags:         req.Tags,
+		AllowHoldout: req.AllowHoldout,
+	}, req.Expression.toEntity())
+	if err != nil {
+		h.l.Error(err)
+		if errors.Is(err, entity.ErrInvalidExpression) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrInvalidExpression.Error()})
+			return
+		}
+		if errors.Is(err, entity.ErrSegmentAlreadyExist) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"msg:": entity.ErrSegmentAlreadyExist.Error()})
+			return
+		}
+		if errors.Is(err, entity.ErrSegmentNotFound) {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"msg:": entity.ErrSegmentNotFound.Error()})
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, responseNewSegmentWithAutoAssign{
+		IDS: ids,
+	})
+}
+
+type requestCloneSegment struct {
+	Slug        string `json:"slug" binding:"required,max=100"`
+	CopyMembers bool   `json:"copy_members"`
+	// copied memberships never expire instead of keeping the source expiration
+	ResetExpiration bool `json:"reset_expiration"`
+}
+
+func (h *segmentHandler) cloneSegment(c *gin.Context) {
+	slug := c.Param("slug")
+
+	var req requestCloneSegment
+	if err := c.BindJSON(&req); err != nil {
+		h.l.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": err.Error()})
+		return
+	}
+
+	ids, err := h.uc.CloneSegment(slug, entity.SegmentClone{
+		Slug:            req.Slug,
+		CopyMembers:     req.CopyMembers,
+		ResetExpiration: req.ResetExpiration,
+	})
+	if err != nil {
+		h.l.Error(err)
+		status := http.StatusInternalServerError
+		respErr := entity.ErrInternalServer
+		switch {
+		case errors.Is(err, entity.ErrSegmentNotFound):
+			status = http.StatusNotFound
+			respErr = entity.ErrSegmentNotFound
+		case errors.Is(err, entity.ErrSegmentAlreadyExist):
+			status = http.StatusConflict
+			respErr = entity.ErrSegmentAlreadyExist
+		case errors.Is(err, entity.ErrSegmentFull):
+			status = http.StatusForbidden
+			respErr = entity.ErrSegmentFull
+		case errors.Is(err, entity.ErrPrerequisiteMissing):
+			status = http.StatusUnprocessableEntity
+			respErr = entity.ErrPrerequisiteMissing
+		}
+		c.AbortWithStatusJSON(status, gin.H{"msg:": respErr.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, responseNewSegmentWithAutoAssign{
+		IDS: ids,
+	})
+}
+
 type requestNewSegmentWithAutoAssign struct {
 	Slug    string `json:"slug" binding:"required,max=100"`
 	Percent int    `json:"percent" binding:"required,numeric,min=0,max=100"`
+	Mode    string `json:"mode" binding:"omitempty,oneof=random hash"`
+	Salt    string `json:"salt" binding:"max=100"`
+	Layer   string `json:"layer" binding:"max=100"`
+	requestSegmentMetadata
+	requestExpiration
 }
 type responseNewSegmentWithAutoAssign struct {
 	IDS []int `json:"ids"`
@@ -87,8 +397,24 @@ func (h *segmentHandler) newSegmentWithAutoAssign(c *gin.Context) {
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": err.Error()})
 		return
 	}
+	if !NewValidator().checkExpirationIsValid(req.TTL, req.ExpiresAt) {
+		h.l.Error(entity.ErrInvalidExpiration)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrInvalidExpiration.Error()})
+		return
+	}
+
 	ids, err := h.uc.NewSegmentWithAutoAssign(entity.Segment{
-		Slug: req.Slug,
+		Slug:         req.Slug,
+		Salt:         req.Salt,
+		AssignMode:   entity.AssignMode(req.Mode),
+		Layer:        req.Layer,
+		Description:  req.Description,
+		Owner:        req.Owner,
+		Tags:         req.Tags,
+		AllowHoldout: req.AllowHoldout,
+		MaxMembers:   req.MaxMembers,
+		// memberships of users enrolled later expire at the same time
+		RolloutExpiresAt: req.expiration(),
 	}, req.Percent)
 	if err != nil {
 		h.l.Error(err)
@@ -96,6 +422,10 @@ func (h *segmentHandler) newSegmentWithAutoAssign(c *gin.Context) {
 			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"msg:": entity.ErrSegmentAlreadyExist.Error()})
 			return
 		}
+		if errors.Is(err, entity.ErrInvalidMaxMembers) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrInvalidMaxMembers.Error()})
+			return
+		}
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
@@ -104,3 +434,436 @@ func (h *segmentHandler) newSegmentWithAutoAssign(c *gin.Context) {
 		IDS: ids,
 	})
 }
+
+type requestSetRollout struct {
+	Percent *int `json:"percent" binding:"required,min=0,max=100"`
+}
+
+type responseSetRollout struct {
+	Added   []int `json:"added"`
+	Removed []int `json:"removed"`
+}
+
+func (h *segmentHandler) setRollout(c *gin.Context) {
+	slug := c.Param("slug")
+
+	var req requestSetRollout
+	if err := c.BindJSON(&req); err != nil {
+		h.l.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": err.Error()})
+		return
+	}
+
+	change, err := h.uc.SetRollout(slug, *req.Percent)
+	if err != nil {
+		h.l.Error(err)
+		if errors.Is(err, entity.ErrSegmentNotFound) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, entity.ErrSegmentArchived) {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"msg:": entity.ErrSegmentArchived.Error()})
+			return
+		}
+		if errors.Is(err, entity.ErrNotRolloutSegment) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"msg:": entity.ErrNotRolloutSegment.Error()})
+			return
+		}
+		if errors.Is(err, entity.ErrPrerequisiteRequired) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"msg:": entity.ErrPrerequisiteRequired.Error()})
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, responseSetRollout{
+		Added:   change.Added,
+		Removed: change.Removed,
+	})
+}
+
+type requestRolloutStep struct {
+	Percent *int      `json:"percent" binding:"required,min=0,max=100"`
+	ApplyAt time.Time `json:"apply_at" binding:"required"`
+}
+
+type requestSetRolloutSchedule struct {
+	Steps []requestRolloutStep `json:"steps" binding:"max=100,dive"`
+}
+
+type responseRolloutStep struct {
+	ID        int        `json:"id"`
+	Percent   int        `json:"percent"`
+	ApplyAt   time.Time  `json:"apply_at"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Attempts  int        `json:"attempts,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+func newResponseRolloutSchedule(steps []entity.RolloutStep) []responseRolloutStep {
+	resp := make([]responseRolloutStep, len(steps))
+	for i, step := range steps {
+		resp[i] = responseRolloutStep{
+			ID:        step.ID,
+			Percent:   step.Percent,
+			ApplyAt:   step.ApplyAt,
+			AppliedAt: step.AppliedAt,
+			Attempts:  step.Attempts,
+			LastError: step.LastError,
+		}
+	}
+	return resp
+}
+
+func (h *segmentHandler) setRolloutSchedule(c *gin.Context) {
+	slug := c.Param("slug")
+
+	var req requestSetRolloutSchedule
+	if err := c.BindJSON(&req); err != nil {
+		h.l.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": err.Error()})
+		return
+	}
+
+	steps := make([]entity.RolloutStep, len(req.Steps))
+	for i, step := range req.Steps {
+		steps[i] = entity.RolloutStep{
+			Percent: *step.Percent,
+			ApplyAt: step.ApplyAt,
+		}
+	}
+
+	schedule, err := h.uc.SetRolloutSchedule(slug, steps)
+	if err != nil {
+		h.l.Error(err)
+		if errors.Is(err, entity.ErrSegmentNotFound) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, entity.ErrInvalidRolloutSchedule) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrInvalidRolloutSchedule.Error()})
+			return
+		}
+		if errors.Is(err, entity.ErrNotRolloutSegment) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"msg:": entity.ErrNotRolloutSegment.Error()})
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, newResponseRolloutSchedule(schedule))
+}
+
+func (h *segmentHandler) rolloutSchedule(c *gin.Context) {
+	slug := c.Param("slug")
+
+	schedule, err := h.uc.RolloutSchedule(slug)
+	if err != nil {
+		h.l.Error(err)
+		if errors.Is(err, entity.ErrSegmentNotFound) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, newResponseRolloutSchedule(schedule))
+}
+
+type responseBackfillRollouts struct {
+	Added int `json:"added"`
+}
+
+func (h *segmentHandler) backfillRollouts(c *gin.Context) {
+	added, err := h.uc.BackfillRollouts()
+	if err != nil {
+		h.l.Error(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, responseBackfillRollouts{
+		Added: added,
+	})
+}
+
+type requestSetHoldout struct {
+	Percent *int   `json:"percent" binding:"required,min=0,max=100"`
+	Salt    string `json:"salt" binding:"max=100"`
+}
+
+type responseHoldout struct {
+	Percent int    `json:"percent"`
+	Salt    string `json:"salt"`
+}
+
+func (h *segmentHandler) setHoldout(c *gin.Context) {
+	var req requestSetHoldout
+	if err := c.BindJSON(&req); err != nil {
+		h.l.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": err.Error()})
+		return
+	}
+
+	holdout, err := h.uc.SetHoldout(entity.Holdout{
+		Salt:    req.Salt,
+		Percent: *req.Percent,
+	})
+	if err != nil {
+		h.l.Error(err)
+		if errors.Is(err, entity.ErrInvalidHoldout) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrInvalidHoldout.Error()})
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, responseHoldout{
+		Percent: holdout.Percent,
+		Salt:    holdout.Salt,
+	})
+}
+
+func (h *segmentHandler) holdout(c *gin.Context) {
+	holdout, err := h.uc.Holdout()
+	if err != nil {
+		h.l.Error(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, responseHoldout{
+		Percent: holdout.Percent,
+		Salt:    holdout.Salt,
+	})
+}
+
+type responseSegment struct {
+	Slug           string `json:"slug"`
+	Layer          string `json:"layer,omitempty"`
+	Rule           string `json:"rule,omitempty"`
+	Experiment     string `json:"experiment,omitempty"`
+	Variant        string `json:"variant,omitempty"`
+	RolloutPercent *int   `json:"rollout_percent,omitempty"`
+	// memberships added by the rollout expire at this time
+	RolloutExpiresAt *time.Time `json:"rollout_expires_at,omitempty"`
+	AssignMode       string     `json:"assign_mode,omitempty"`
+	Salt             string     `json:"salt,omitempty"`
+	Description      string     `json:"description"`
+	Owner            string     `json:"owner"`
+	Tags             []string   `json:"tags"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	ArchivedAt       *time.Time `json:"archived_at,omitempty"`
+	StartsAt         *time.Time `json:"starts_at,omitempty"`
+	EndsAt           *time.Time `json:"ends_at,omitempty"`
+	AllowHoldout     bool       `json:"allow_holdout"`
+	Prerequisites    []string   `json:"prerequisites"`
+	RemovalPolicy    string     `json:"removal_policy"`
+	MaxMembers       *int       `json:"max_members,omitempty"`
+	Members          int        `json:"members"` // active (non-expired) members
+}
+
+func newResponseSegment(seg entity.SegmentInfo) responseSegment {
+	return responseSegment{
+		Slug:             seg.Slug,
+		Layer:            seg.Layer,
+		Rule:             seg.Rule,
+		Experiment:       seg.Experiment,
+		Variant:          seg.Variant,
+		RolloutPercent:   seg.RolloutPercent,
+		RolloutExpiresAt: seg.RolloutExpiresAt,
+		AssignMode:       string(seg.AssignMode),
+		Salt:             seg.Salt,
+		Description:      seg.Description,
+		Owner:            seg.Owner,
+		Tags:             seg.Tags,
+		CreatedAt:        seg.CreatedAt,
+		UpdatedAt:        seg.UpdatedAt,
+		ArchivedAt:       seg.ArchivedAt,
+		StartsAt:         seg.StartsAt,
+		EndsAt:           seg.EndsAt,
+		AllowHoldout:     seg.AllowHoldout,
+		Prerequisites:    seg.Prerequisites,
+		RemovalPolicy:    string(seg.RemovalPolicy),
+		MaxMembers:       seg.MaxMembers,
+		Members:          seg.Members,
+	}
+}
+
+type requestSegments struct {
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset int    `form:"offset" binding:"omitempty,min=0"`
+	Prefix string `form:"prefix" binding:"max=100"`
+	Sort   string `form:"sort" binding:"omitempty,oneof=slug members"`
+	Order  string `form:"order" binding:"omitempty,oneof=asc desc"`
+	// list archived segments instead of active ones
+	Archived bool `form:"archived"`
+}
+
+type responseSegments struct {
+	Total    int               `json:"total"`
+	Segments []responseSegment `json:"segments"`
+}
+
+func (h *segmentHandler) segments(c *gin.Context) {
+	var req requestSegments
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.l.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": err.Error()})
+		return
+	}
+
+	segments, total, err := h.uc.Segments(entity.SegmentFilter{
+		Prefix:   req.Prefix,
+		Sort:     entity.SegmentSort(req.Sort),
+		Desc:     req.Order == "desc",
+		Archived: req.Archived,
+		Limit:    req.Limit,
+		Offset:   req.Offset,
+	})
+	if err != nil {
+		h.l.Error(err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	resp := responseSegments{
+		Total:    total,
+		Segments: make([]responseSegment, len(segments)),
+	}
+	for i, seg := range segments {
+		resp.Segments[i] = newResponseSegment(seg)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *segmentHandler) segment(c *gin.Context) {
+	slug := c.Param("slug")
+
+	seg, err := h.uc.Segment(slug)
+	if err != nil {
+		h.l.Error(err)
+		if errors.Is(err, entity.ErrSegmentNotFound) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, newResponseSegment(seg))
+}
+
+type requestUpdateSegment struct {
+	Description  *string  `json:"description" binding:"omitempty,max=1000"`
+	Owner        *string  `json:"owner" binding:"omitempty,max=100"`
+	Tags         []string `json:"tags" binding:"omitempty,max=20,dive,required,max=50"`
+	AllowHoldout *bool    `json:"allow_holdout"`
+	// replaces all prerequisites of the segment, an empty list removes them
+	Prerequisites []string `json:"prerequisites" binding:"omitempty,max=20,dive,required,max=100"`
+	RemovalPolicy *string  `json:"removal_policy" binding:"omitempty,oneof=block cascade"`
+	MaxMembers    *int     `json:"max_members" binding:"omitempty,min=1"`
+	requestSegmentWindow
+	// removes starts_at and ends_at, so the segment is always active
+	ClearWindow bool `json:"clear_window"`
+	// removes max_members, so the segment is unlimited
+	ClearMaxMembers bool `json:"clear_max_members"`
+}
+
+func (h *segmentHandler) updateSegment(c *gin.Context) {
+	slug := c.Param("slug")
+
+	var req requestUpdateSegment
+	if err := c.BindJSON(&req); err != nil {
+		h.l.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": err.Error()})
+		return
+	}
+
+	var removalPolicy *entity.RemovalPolicy
+	if req.RemovalPolicy != nil {
+		policy := entity.RemovalPolicy(*req.RemovalPolicy)
+		removalPolicy = &policy
+	}
+
+	seg, err := h.uc.UpdateSegment(slug, entity.SegmentUpdate{
+		Description:     req.Description,
+		Owner:           req.Owner,
+		Tags:            req.Tags,
+		StartsAt:        req.StartsAt,
+		EndsAt:          req.EndsAt,
+		ClearWindow:     req.ClearWindow,
+		AllowHoldout:    req.AllowHoldout,
+		Prerequisites:   req.Prerequisites,
+		RemovalPolicy:   removalPolicy,
+		MaxMembers:      req.MaxMembers,
+		ClearMaxMembers: req.ClearMaxMembers,
+	})
+	if err != nil {
+		h.l.Error(err)
+		if errors.Is(err, entity.ErrSegmentNotFound) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, entity.ErrInvalidSegmentWindow) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrInvalidSegmentWindow.Error()})
+			return
+		}
+		if errors.Is(err, entity.ErrPrerequisiteCycle) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrPrerequisiteCycle.Error()})
+			return
+		}
+		if errors.Is(err, entity.ErrInvalidMaxMembers) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrInvalidMaxMembers.Error()})
+			return
+		}
+		if errors.Is(err, entity.ErrPrerequisiteNotFound) {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"msg:": entity.ErrPrerequisiteNotFound.Error()})
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, newResponseSegment(seg))
+}
+
+type responseSegmentChange struct {
+	ID        int            `json:"id"`
+	OldValues map[string]any `json:"old_values"`
+	NewValues map[string]any `json:"new_values"`
+	Date      time.Time      `json:"date"`
+}
+
+func (h *segmentHandler) segmentChanges(c *gin.Context) {
+	slug := c.Param("slug")
+
+	changes, err := h.uc.SegmentChanges(slug)
+	if err != nil {
+		h.l.Error(err)
+		if errors.Is(err, entity.ErrSegmentNotFound) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]responseSegmentChange, len(changes))
+	for i, change := range changes {
+		resp[i] = responseSegmentChange{
+			ID:        change.ID,
+			OldValues: change.OldValues,
+			NewValues: change.NewValues,
+			Date:      change.Date,
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
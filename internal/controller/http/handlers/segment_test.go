@@ -40,6 +40,60 @@ func TestNewSegment(t *testing.T) {
 			errUsecase:     errors.New("unexpected error"),
 			expectedStatus: http.StatusInternalServerError,
 		},
+		{
+			name:           "Invalid targeting rule",
+			reqJSON:        `{"slug": "slug-name", "rule": "country = RU"}`,
+			errUsecase:     entity.ErrInvalidRule,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Segment in a layer",
+			reqJSON:        `{"slug": "slug-name", "layer": "checkout"}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Activation window ends before it starts",
+			reqJSON:        `{"slug": "slug-name", "starts_at": "2023-09-08T00:00:00Z", "ends_at": "2023-09-01T00:00:00Z"}`,
+			errUsecase:     entity.ErrInvalidSegmentWindow,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Prerequisites",
+			reqJSON:        `{"slug": "CHECKOUT_V2_PROMO", "prerequisites": ["CHECKOUT_V2"], "removal_policy": "cascade"}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Prerequisites cycle",
+			reqJSON:        `{"slug": "slug-name", "prerequisites": ["slug-name"]}`,
+			errUsecase:     entity.ErrPrerequisiteCycle,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Non-existent prerequisite",
+			reqJSON:        `{"slug": "slug-name", "prerequisites": ["CHECKOUT_V3"]}`,
+			errUsecase:     entity.ErrPrerequisiteNotFound,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "Limited segment",
+			reqJSON:        `{"slug": "AVITO_LIMITED_OFFER", "max_members": 1000}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Zero max members",
+			reqJSON:        `{"slug": "AVITO_LIMITED_OFFER", "max_members": 0}`,
+			errUsecase:     entity.ErrInvalidMaxMembers,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown removal policy",
+			reqJSON:        `{"slug": "slug-name", "prerequisites": ["CHECKOUT_V2"], "removal_policy": "ignore"}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name:           "Invalid request",
 			reqJSON:        `{"slugggg": "slug-name"}`,
@@ -67,7 +121,7 @@ func TestNewSegment(t *testing.T) {
 	}
 }
 
-func TestDeleteSegments(t *testing.T) {
+func TestArchiveSegment(t *testing.T) {
 	testCase := []struct {
 		name           string
 		slug           string
@@ -103,13 +157,13 @@ func TestDeleteSegments(t *testing.T) {
 			uc: mockUsecase,
 			l:  logger,
 		}
-		mockUsecase.On("DeleteSegment", mock.Anything).Return(tc.errUsecase)
+		mockUsecase.On("ArchiveSegment", mock.Anything).Return(tc.errUsecase)
 
 		mockContext.Params = []gin.Param{{Key: "slug", Value: tc.slug}}
 		mockContext.Request = httptest.NewRequest("DELETE", "/segments/"+tc.slug+"/", nil)
 		mockContext.Request.Header.Set("Accept", "application/json")
 
-		handler.deleteSegment(mockContext)
+		handler.archiveSegment(mockContext)
 		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
 	}
 }
@@ -144,6 +198,42 @@ func TestNewSegmentWithAutoAssign(t *testing.T) {
 			errUsecase:     nil,
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name:           "Hash mode",
+			reqJSON:        `{"slug": "slug-name", "percent": 10, "mode": "hash", "salt": "salt"}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Unknown mode",
+			reqJSON:        `{"slug": "slug-name", "percent": 10, "mode": "round-robin"}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "With ttl",
+			reqJSON:        `{"slug": "slug-name", "percent": 10, "ttl": 14}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "With expires_at",
+			reqJSON:        `{"slug": "slug-name", "percent": 10, "expires_at": "2100-01-01T00:00:00Z"}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Both ttl and expires_at",
+			reqJSON:        `{"slug": "slug-name", "percent": 10, "ttl": 14, "expires_at": "2100-01-01T00:00:00Z"}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "expires_at in the past",
+			reqJSON:        `{"slug": "slug-name", "percent": 10, "expires_at": "2020-01-01T00:00:00Z"}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -164,3 +254,884 @@ func TestNewSegmentWithAutoAssign(t *testing.T) {
 		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
 	}
 }
+
+func TestNewSegmentFromExpression(t *testing.T) {
+	testCases := []struct {
+		name           string
+		reqJSON        string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			reqJSON:        `{"slug": "A_NOT_B", "expression": {"op": "difference", "args": [{"slug": "A"}, {"slug": "B"}]}}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Invalid expression",
+			reqJSON:        `{"slug": "A_XOR_B", "expression": {"op": "xor", "args": [{"slug": "A"}, {"slug": "B"}]}}`,
+			errUsecase:     entity.ErrInvalidExpression,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Non-existent segment",
+			reqJSON:        `{"slug": "A_AND_Z", "expression": {"op": "intersect", "args": [{"slug": "A"}, {"slug": "Z"}]}}`,
+			errUsecase:     entity.ErrSegmentNotFound,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "Segment already exists",
+			reqJSON:        `{"slug": "A", "expression": {"op": "union", "args": [{"slug": "A"}, {"slug": "B"}]}}`,
+			errUsecase:     entity.ErrSegmentAlreadyExist,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Missing slug",
+			reqJSON:        `{"expression": {"slug": "A"}}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unexpected usecase error",
+			reqJSON:        `{"slug": "A_OR_B", "expression": {"op": "union", "args": [{"slug": "A"}, {"slug": "B"}]}}`,
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.SegmentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := segmentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("NewSegmentFromExpression", mock.Anything, mock.Anything).Return([]int{1}, tc.errUsecase)
+
+		mockContext.Request = httptest.NewRequest("POST", "/segments/compose", strings.NewReader(tc.reqJSON))
+		mockContext.Request.Header.Set("Accept", "application/json")
+
+		handler.newSegmentFromExpression(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
+func TestAutoAssignSegment(t *testing.T) {
+	testCases := []struct {
+		name           string
+		slug           string
+		reqJSON        string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			slug:           "AVITO_DISCOUNT_30",
+			reqJSON:        `{"percent": 10}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "With ttl",
+			slug:           "AVITO_DISCOUNT_30",
+			reqJSON:        `{"percent": 10, "ttl": 7}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "With expires_at",
+			slug:           "AVITO_DISCOUNT_30",
+			reqJSON:        `{"percent": 10, "expires_at": "2100-01-01T00:00:00Z"}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Both ttl and expires_at",
+			slug:           "AVITO_DISCOUNT_30",
+			reqJSON:        `{"percent": 10, "ttl": 7, "expires_at": "2100-01-01T00:00:00Z"}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Negative ttl",
+			slug:           "AVITO_DISCOUNT_30",
+			reqJSON:        `{"percent": 10, "ttl": -1}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Percent is more then 100",
+			slug:           "AVITO_DISCOUNT_30",
+			reqJSON:        `{"percent": 101}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Non-existent slug",
+			slug:           "AVITO_DISCOUNT_30",
+			reqJSON:        `{"percent": 10}`,
+			errUsecase:     entity.ErrSegmentNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Archived segment",
+			slug:           "AVITO_DISCOUNT_30",
+			reqJSON:        `{"percent": 10}`,
+			errUsecase:     entity.ErrSegmentArchived,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.SegmentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := segmentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("AutoAssignSegment", mock.Anything, mock.Anything, mock.Anything).Return([]int{1}, tc.errUsecase)
+
+		mockContext.Params = []gin.Param{{Key: "slug", Value: tc.slug}}
+		mockContext.Request = httptest.NewRequest("POST", "/segments/"+tc.slug+"/auto-assign", strings.NewReader(tc.reqJSON))
+		mockContext.Request.Header.Set("Accept", "application/json")
+
+		handler.autoAssignSegment(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
+func TestCloneSegment(t *testing.T) {
+	testCases := []struct {
+		name           string
+		slug           string
+		reqJSON        string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			slug:           "CHECKOUT_V2",
+			reqJSON:        `{"slug": "CHECKOUT_V3", "copy_members": true, "reset_expiration": true}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Non-existent source",
+			slug:           "CHECKOUT_V2",
+			reqJSON:        `{"slug": "CHECKOUT_V3"}`,
+			errUsecase:     entity.ErrSegmentNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Slug is taken",
+			slug:           "CHECKOUT_V2",
+			reqJSON:        `{"slug": "CHECKOUT_V2"}`,
+			errUsecase:     entity.ErrSegmentAlreadyExist,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Clone does not fit max members",
+			slug:           "CHECKOUT_V2",
+			reqJSON:        `{"slug": "CHECKOUT_V3", "copy_members": true}`,
+			errUsecase:     entity.ErrSegmentFull,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Missing slug",
+			slug:           "CHECKOUT_V2",
+			reqJSON:        `{"copy_members": true}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unexpected usecase error",
+			slug:           "CHECKOUT_V2",
+			reqJSON:        `{"slug": "CHECKOUT_V3"}`,
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.SegmentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := segmentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("CloneSegment", mock.Anything, mock.Anything).Return([]int{1}, tc.errUsecase)
+
+		mockContext.Params = []gin.Param{{Key: "slug", Value: tc.slug}}
+		mockContext.Request = httptest.NewRequest("POST", "/segments/"+tc.slug+"/clone", strings.NewReader(tc.reqJSON))
+		mockContext.Request.Header.Set("Accept", "application/json")
+
+		handler.cloneSegment(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
+func TestBackfillRollouts(t *testing.T) {
+	testCases := []struct {
+		name           string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Unexpected usecase error",
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.SegmentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := segmentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("BackfillRollouts").Return(3, tc.errUsecase)
+
+		mockContext.Request = httptest.NewRequest("POST", "/segments/rollouts/backfill", nil)
+		mockContext.Request.Header.Set("Accept", "application/json")
+
+		handler.backfillRollouts(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
+func TestSegments(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			query:          "",
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Search with pagination and sorting",
+			query:          "?prefix=AVITO&limit=10&offset=20&sort=members&order=desc",
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Unknown sort",
+			query:          "?sort=owner",
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Limit is too big",
+			query:          "?limit=1000",
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unexpected usecase error",
+			query:          "",
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.SegmentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := segmentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("Segments", mock.Anything).Return([]entity.SegmentInfo{{Segment: entity.Segment{Slug: "AVITO_DISCOUNT_30"}}}, 1, tc.errUsecase)
+
+		mockContext.Request = httptest.NewRequest("GET", "/segments"+tc.query, nil)
+		mockContext.Request.Header.Set("Accept", "application/json")
+
+		handler.segments(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
+func TestSegment(t *testing.T) {
+	testCases := []struct {
+		name           string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Non-existent slug",
+			errUsecase:     entity.ErrSegmentNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Unexpected usecase error",
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.SegmentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := segmentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("Segment", mock.Anything).Return(entity.SegmentInfo{Segment: entity.Segment{Slug: "slug"}}, tc.errUsecase)
+
+		mockContext.Params = []gin.Param{{Key: "slug", Value: "slug"}}
+		mockContext.Request = httptest.NewRequest("GET", "/segments/slug", nil)
+		mockContext.Request.Header.Set("Accept", "application/json")
+
+		handler.segment(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
+func TestUpdateSegment(t *testing.T) {
+	testCases := []struct {
+		name           string
+		reqJSON        string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			reqJSON:        `{"description": "30% discount", "owner": "pricing", "tags": ["promo"]}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Empty tag",
+			reqJSON:        `{"tags": [""]}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid activation window",
+			reqJSON:        `{"starts_at": "2023-09-08T00:00:00Z", "ends_at": "2023-09-01T00:00:00Z"}`,
+			errUsecase:     entity.ErrInvalidSegmentWindow,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Clear activation window",
+			reqJSON:        `{"clear_window": true}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Clear max members",
+			reqJSON:        `{"clear_max_members": true}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Non-existent slug",
+			reqJSON:        `{"owner": "pricing"}`,
+			errUsecase:     entity.ErrSegmentNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Replace prerequisites",
+			reqJSON:        `{"prerequisites": ["CHECKOUT_V2"], "removal_policy": "block"}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Prerequisites cycle",
+			reqJSON:        `{"prerequisites": ["CHECKOUT_V2_PROMO"]}`,
+			errUsecase:     entity.ErrPrerequisiteCycle,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unexpected usecase error",
+			reqJSON:        `{"owner": "pricing"}`,
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.SegmentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := segmentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("UpdateSegment", mock.Anything, mock.Anything).Return(entity.SegmentInfo{Segment: entity.Segment{Slug: "slug"}}, tc.errUsecase)
+
+		mockContext.Params = []gin.Param{{Key: "slug", Value: "slug"}}
+		mockContext.Request = httptest.NewRequest("PATCH", "/segments/slug", strings.NewReader(tc.reqJSON))
+		mockContext.Request.Header.Set("Content-Type", "application/json")
+
+		handler.updateSegment(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
+func TestSegmentChanges(t *testing.T) {
+	testCases := []struct {
+		name           string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Non-existent slug",
+			errUsecase:     entity.ErrSegmentNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Unexpected usecase error",
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.SegmentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := segmentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("SegmentChanges", mock.Anything).Return([]entity.SegmentChange{}, tc.errUsecase)
+
+		mockContext.Params = []gin.Param{{Key: "slug", Value: "slug"}}
+		mockContext.Request = httptest.NewRequest("GET", "/segments/slug/changes", nil)
+		mockContext.Request.Header.Set("Accept", "application/json")
+
+		handler.segmentChanges(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
+func TestRestoreSegment(t *testing.T) {
+	testCases := []struct {
+		name           string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Non-existent slug",
+			errUsecase:     entity.ErrSegmentNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Segment is not archived",
+			errUsecase:     entity.ErrSegmentNotArchived,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Unexpected usecase error",
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.SegmentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := segmentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("RestoreSegment", mock.Anything).Return(tc.errUsecase)
+
+		mockContext.Params = []gin.Param{{Key: "slug", Value: "slug"}}
+		mockContext.Request = httptest.NewRequest("POST", "/segments/slug/restore", nil)
+		mockContext.Request.Header.Set("Accept", "application/json")
+
+		handler.restoreSegment(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
+func TestPurgeSegment(t *testing.T) {
+	testCases := []struct {
+		name           string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Non-existent slug",
+			errUsecase:     entity.ErrSegmentNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Segment is not archived",
+			errUsecase:     entity.ErrSegmentNotArchived,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Unexpected usecase error",
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.SegmentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := segmentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("PurgeSegment", mock.Anything).Return(tc.errUsecase)
+
+		mockContext.Params = []gin.Param{{Key: "slug", Value: "slug"}}
+		mockContext.Request = httptest.NewRequest("DELETE", "/admin/segments/slug", nil)
+		mockContext.Request.Header.Set("Accept", "application/json")
+
+		handler.purgeSegment(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
+func TestSetRollout(t *testing.T) {
+	testCases := []struct {
+		name           string
+		reqJSON        string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			reqJSON:        `{"percent": 20}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Zero percent",
+			reqJSON:        `{"percent": 0}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Missing percent",
+			reqJSON:        `{}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Percent out of range",
+			reqJSON:        `{"percent": 101}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Non-existent slug",
+			reqJSON:        `{"percent": 20}`,
+			errUsecase:     entity.ErrSegmentNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Archived segment",
+			reqJSON:        `{"percent": 20}`,
+			errUsecase:     entity.ErrSegmentArchived,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "Manual segment",
+			reqJSON:        `{"percent": 20}`,
+			errUsecase:     entity.ErrNotRolloutSegment,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Removed member has a blocking dependent",
+			reqJSON:        `{"percent": 10}`,
+			errUsecase:     entity.ErrPrerequisiteRequired,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Unexpected usecase error",
+			reqJSON:        `{"percent": 20}`,
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.SegmentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := segmentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("SetRollout", mock.Anything, mock.Anything).Return(entity.RolloutChange{Added: []int{1}, Removed: []int{}}, tc.errUsecase)
+
+		mockContext.Params = []gin.Param{{Key: "slug", Value: "slug"}}
+		mockContext.Request = httptest.NewRequest("PATCH", "/segments/slug/rollout", strings.NewReader(tc.reqJSON))
+		mockContext.Request.Header.Set("Content-Type", "application/json")
+
+		handler.setRollout(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
+func TestSetRolloutSchedule(t *testing.T) {
+	testCases := []struct {
+		name           string
+		reqJSON        string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name: "Success",
+			reqJSON: `{"steps": [
+				{"percent": 10, "apply_at": "2023-09-18T10:00:00Z"},
+				{"percent": 30, "apply_at": "2023-09-20T10:00:00Z"},
+				{"percent": 100, "apply_at": "2023-09-25T10:00:00Z"}
+			]}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Clear schedule",
+			reqJSON:        `{"steps": []}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Step without apply_at",
+			reqJSON:        `{"steps": [{"percent": 10}]}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Percent out of range",
+			reqJSON:        `{"steps": [{"percent": 110, "apply_at": "2023-09-18T10:00:00Z"}]}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Steps at the same time",
+			reqJSON: `{"steps": [
+				{"percent": 10, "apply_at": "2023-09-18T10:00:00Z"},
+				{"percent": 30, "apply_at": "2023-09-18T10:00:00Z"}
+			]}`,
+			errUsecase:     entity.ErrInvalidRolloutSchedule,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Non-existent slug",
+			reqJSON:        `{"steps": [{"percent": 10, "apply_at": "2023-09-18T10:00:00Z"}]}`,
+			errUsecase:     entity.ErrSegmentNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Variant segment",
+			reqJSON:        `{"steps": [{"percent": 10, "apply_at": "2023-09-18T10:00:00Z"}]}`,
+			errUsecase:     entity.ErrNotRolloutSegment,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Unexpected usecase error",
+			reqJSON:        `{"steps": [{"percent": 10, "apply_at": "2023-09-18T10:00:00Z"}]}`,
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.SegmentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := segmentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("SetRolloutSchedule", mock.Anything, mock.Anything).Return([]entity.RolloutStep{}, tc.errUsecase)
+
+		mockContext.Params = []gin.Param{{Key: "slug", Value: "slug"}}
+		mockContext.Request = httptest.NewRequest("PUT", "/segments/slug/rollout/schedule", strings.NewReader(tc.reqJSON))
+		mockContext.Request.Header.Set("Content-Type", "application/json")
+
+		handler.setRolloutSchedule(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
+func TestRolloutSchedule(t *testing.T) {
+	testCases := []struct {
+		name           string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Non-existent slug",
+			errUsecase:     entity.ErrSegmentNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Unexpected usecase error",
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.SegmentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := segmentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("RolloutSchedule", mock.Anything).Return([]entity.RolloutStep{}, tc.errUsecase)
+
+		mockContext.Params = []gin.Param{{Key: "slug", Value: "slug"}}
+		mockContext.Request = httptest.NewRequest("GET", "/segments/slug/rollout/schedule", nil)
+		mockContext.Request.Header.Set("Accept", "application/json")
+
+		handler.rolloutSchedule(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
+func TestSetHoldout(t *testing.T) {
+	testCases := []struct {
+		name           string
+		reqJSON        string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			reqJSON:        `{"percent": 5, "salt": "holdout-2023"}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Disable holdout",
+			reqJSON:        `{"percent": 0}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Missing percent",
+			reqJSON:        `{"salt": "holdout-2023"}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unexpected usecase error",
+			reqJSON:        `{"percent": 5}`,
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.SegmentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := segmentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("SetHoldout", mock.Anything).Return(entity.Holdout{Salt: "holdout", Percent: 5}, tc.errUsecase)
+
+		mockContext.Request = httptest.NewRequest("PUT", "/holdout", strings.NewReader(tc.reqJSON))
+		mockContext.Request.Header.Set("Content-Type", "application/json")
+
+		handler.setHoldout(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
+func TestHoldout(t *testing.T) {
+	testCases := []struct {
+		name           string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Unexpected usecase error",
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		logger := logger.New()
+		mockUsecase := new(mocks.SegmentUsecase)
+		mockContext := newMockGinContext()
+
+		handler := segmentHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("Holdout").Return(entity.Holdout{Salt: "holdout", Percent: 5}, tc.errUsecase)
+
+		mockContext.Request = httptest.NewRequest("GET", "/holdout", nil)
+		mockContext.Request.Header.Set("Accept", "application/json")
+
+		handler.holdout(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
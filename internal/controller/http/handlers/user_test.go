@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"experiment.io/internal/entity"
 	"experiment.io/internal/mocks"
@@ -22,6 +24,7 @@ func TestEditUserSegments(t *testing.T) {
 		reqJSON           string
 		errUsecaseAdded   error
 		errUsecaseRemoved error
+		errUsecaseUpdated error
 		expectedStatus    int
 	}{
 		{
@@ -115,6 +118,66 @@ func TestEditUserSegments(t *testing.T) {
 			errUsecaseRemoved: nil,
 			expectedStatus:    http.StatusConflict,
 		},
+		{
+			name:   "User already assigned another variant",
+			userID: "1",
+			reqJSON: `{
+				"add_segments": 
+				[{
+					"slug": "CHECKOUT_treatment",
+					"ttl": 7
+				}],
+				"remove_segments": ["segment2"]
+				}`,
+			errUsecaseAdded:   entity.ErrVariantConflict,
+			errUsecaseRemoved: nil,
+			expectedStatus:    http.StatusConflict,
+		},
+		{
+			name:   "User already assigned another segment of the layer",
+			userID: "1",
+			reqJSON: `{
+				"add_segments": 
+				[{
+					"slug": "CHECKOUT_DISCOUNT",
+					"ttl": 7
+				}],
+				"remove_segments": ["segment2"]
+				}`,
+			errUsecaseAdded:   entity.ErrLayerConflict,
+			errUsecaseRemoved: nil,
+			expectedStatus:    http.StatusConflict,
+		},
+		{
+			name:   "Archived segment",
+			userID: "1",
+			reqJSON: `{
+				"add_segments": 
+				[{
+					"slug": "OLD_PROMO",
+					"ttl": 7
+				}],
+				"remove_segments": ["segment2"]
+				}`,
+			errUsecaseAdded:   entity.ErrSegmentArchived,
+			errUsecaseRemoved: nil,
+			expectedStatus:    http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "User in holdout",
+			userID: "1",
+			reqJSON: `{
+				"add_segments": 
+				[{
+					"slug": "CHECKOUT_DISCOUNT",
+					"ttl": 7
+				}],
+				"remove_segments": ["segment2"]
+				}`,
+			errUsecaseAdded:   entity.ErrUserInHoldout,
+			errUsecaseRemoved: nil,
+			expectedStatus:    http.StatusConflict,
+		},
 		{
 			name:   "Invalid json",
 			userID: "1",
@@ -144,6 +207,105 @@ func TestEditUserSegments(t *testing.T) {
 			errUsecaseRemoved: nil,
 			expectedStatus:    http.StatusBadRequest,
 		},
+		{
+			name:   "ISO 8601 ttl",
+			userID: "1",
+			reqJSON: `{
+				"add_segments": 
+				[{
+					"slug": "FLASH_SALE",
+					"ttl": "PT6H"
+				}]
+				}`,
+			errUsecaseAdded:   nil,
+			errUsecaseRemoved: nil,
+			expectedStatus:    http.StatusOK,
+		},
+		{
+			name:   "Absolute expiration",
+			userID: "1",
+			reqJSON: `{
+				"add_segments": 
+				[{
+					"slug": "FLASH_SALE",
+					"expires_at": "2100-01-01T00:00:00Z"
+				}]
+				}`,
+			errUsecaseAdded:   nil,
+			errUsecaseRemoved: nil,
+			expectedStatus:    http.StatusOK,
+		},
+		{
+			name:   "Both ttl and expires_at",
+			userID: "1",
+			reqJSON: `{
+				"add_segments": 
+				[{
+					"slug": "FLASH_SALE",
+					"ttl": "P1D",
+					"expires_at": "2100-01-01T00:00:00Z"
+				}]
+				}`,
+			errUsecaseAdded:   nil,
+			errUsecaseRemoved: nil,
+			expectedStatus:    http.StatusBadRequest,
+		},
+		{
+			name:   "expires_at in the past",
+			userID: "1",
+			reqJSON: `{
+				"add_segments": 
+				[{
+					"slug": "FLASH_SALE",
+					"expires_at": "2020-01-01T00:00:00Z"
+				}]
+				}`,
+			errUsecaseAdded:   nil,
+			errUsecaseRemoved: nil,
+			expectedStatus:    http.StatusBadRequest,
+		},
+		{
+			name:   "ttl in months",
+			userID: "1",
+			reqJSON: `{
+				"add_segments": 
+				[{
+					"slug": "FLASH_SALE",
+					"ttl": "P1M"
+				}]
+				}`,
+			errUsecaseAdded:   nil,
+			errUsecaseRemoved: nil,
+			expectedStatus:    http.StatusBadRequest,
+		},
+		{
+			name:   "ttl is more than 366 days",
+			userID: "1",
+			reqJSON: `{
+				"add_segments": 
+				[{
+					"slug": "FLASH_SALE",
+					"ttl": "P53W"
+				}]
+				}`,
+			errUsecaseAdded:   nil,
+			errUsecaseRemoved: nil,
+			expectedStatus:    http.StatusBadRequest,
+		},
+		{
+			name:   "ttl overflows the duration",
+			userID: "1",
+			reqJSON: `{
+				"add_segments": 
+				[{
+					"slug": "FLASH_SALE",
+					"ttl": 213504
+				}]
+				}`,
+			errUsecaseAdded:   nil,
+			errUsecaseRemoved: nil,
+			expectedStatus:    http.StatusBadRequest,
+		},
 		{
 			name:   "Non-existent removed segment",
 			userID: "1",
@@ -159,6 +321,156 @@ func TestEditUserSegments(t *testing.T) {
 			errUsecaseRemoved: entity.ErrUserToSegmentNotFound,
 			expectedStatus:    http.StatusNotFound,
 		},
+		{
+			name:   "Missing prerequisite",
+			userID: "1",
+			reqJSON: `{
+				"add_segments": 
+				[{
+					"slug": "CHECKOUT_V2_PROMO",
+					"ttl": 7
+				}]
+				}`,
+			errUsecaseAdded:   entity.ErrPrerequisiteMissing,
+			errUsecaseRemoved: nil,
+			expectedStatus:    http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "Segment is full",
+			userID: "1",
+			reqJSON: `{
+				"add_segments": 
+				[{
+					"slug": "AVITO_LIMITED_OFFER",
+					"ttl": 7
+				}]
+				}`,
+			errUsecaseAdded:   entity.ErrSegmentFull,
+			errUsecaseRemoved: nil,
+			expectedStatus:    http.StatusForbidden,
+		},
+		{
+			name:   "Removed segment is a prerequisite",
+			userID: "1",
+			reqJSON: `{
+				"remove_segments": ["CHECKOUT_V2"]
+				}`,
+			errUsecaseAdded:   nil,
+			errUsecaseRemoved: entity.ErrPrerequisiteRequired,
+			expectedStatus:    http.StatusConflict,
+		},
+		{
+			name:   "Extend membership",
+			userID: "1",
+			reqJSON: `{
+				"update_segments": 
+				[{
+					"slug": "segment1",
+					"ttl": "P2W"
+				}],
+				"remove_segments": ["segment2"]
+				}`,
+			errUsecaseAdded:   nil,
+			errUsecaseRemoved: nil,
+			errUsecaseUpdated: nil,
+			expectedStatus:    http.StatusOK,
+		},
+		{
+			name:   "Updated and removed segments intersect",
+			userID: "1",
+			reqJSON: `{
+				"update_segments": 
+				[{
+					"slug": "segment1",
+					"ttl": 7
+				}],
+				"remove_segments": ["segment1"]
+				}`,
+			errUsecaseAdded:   nil,
+			errUsecaseRemoved: nil,
+			errUsecaseUpdated: nil,
+			expectedStatus:    http.StatusBadRequest,
+		},
+		{
+			name:   "Updated and added segments intersect",
+			userID: "1",
+			reqJSON: `{
+				"add_segments": 
+				[{
+					"slug": "segment1",
+					"ttl": 7
+				}],
+				"update_segments": 
+				[{
+					"slug": "segment1",
+					"ttl": 14
+				}]
+				}`,
+			errUsecaseAdded:   nil,
+			errUsecaseRemoved: nil,
+			errUsecaseUpdated: nil,
+			expectedStatus:    http.StatusBadRequest,
+		},
+		{
+			name:   "Updated segment expiration in the past",
+			userID: "1",
+			reqJSON: `{
+				"update_segments": 
+				[{
+					"slug": "segment1",
+					"expires_at": "2020-01-01T00:00:00Z"
+				}]
+				}`,
+			errUsecaseAdded:   nil,
+			errUsecaseRemoved: nil,
+			errUsecaseUpdated: nil,
+			expectedStatus:    http.StatusBadRequest,
+		},
+		{
+			name:   "Updated segment is full",
+			userID: "1",
+			reqJSON: `{
+				"update_segments": 
+				[{
+					"slug": "AVITO_LIMITED_OFFER",
+					"ttl": 7
+				}]
+				}`,
+			errUsecaseAdded:   nil,
+			errUsecaseRemoved: nil,
+			errUsecaseUpdated: entity.ErrSegmentFull,
+			expectedStatus:    http.StatusForbidden,
+		},
+		{
+			name:   "Extended segment is archived",
+			userID: "1",
+			reqJSON: `{
+				"update_segments": 
+				[{
+					"slug": "AVITO_DISCOUNT_30",
+					"ttl": 7
+				}]
+				}`,
+			errUsecaseAdded:   nil,
+			errUsecaseRemoved: nil,
+			errUsecaseUpdated: entity.ErrSegmentArchived,
+			expectedStatus:    http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "Shortened prerequisite has a blocking dependent",
+			userID: "1",
+			reqJSON: `{
+				"update_segments": 
+				[{
+					"slug": "CHECKOUT_V2",
+					"ttl": 1
+				}]
+				}`,
+			errUsecaseAdded:   nil,
+			errUsecaseRemoved: nil,
+			errUsecaseUpdated: entity.ErrPrerequisiteRequired,
+			expectedStatus:    http.StatusConflict,
+		},
 	}
 
 	for _, tc := range testCase {
@@ -172,6 +484,7 @@ func TestEditUserSegments(t *testing.T) {
 		}
 		mockUsecase.On("RemoveUserSegments", mock.Anything, mock.Anything).Return(tc.errUsecaseRemoved)
 		mockUsecase.On("AddUserSegments", mock.Anything, mock.Anything).Return(tc.errUsecaseAdded)
+		mockUsecase.On("UpdateUserSegments", mock.Anything, mock.Anything).Return(tc.errUsecaseUpdated)
 
 		mockContext.Params = []gin.Param{{Key: "user_id", Value: tc.userID}}
 		mockContext.Request = httptest.NewRequest("PATCH", "/users/"+tc.userID+"/segments", strings.NewReader(tc.reqJSON))
@@ -187,7 +500,9 @@ func TestUserSegments(t *testing.T) {
 	testCase := []struct {
 		name           string
 		userID         string
+		query          string
 		errUsecase     error
+		errUsecaseAt   error
 		expectedStatus int
 	}{
 		{
@@ -214,6 +529,41 @@ func TestUserSegments(t *testing.T) {
 			errUsecase:     nil,
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name:           "Segments at a past time",
+			userID:         "1",
+			query:          "?at=2023-08-15T12:00:00Z",
+			errUsecaseAt:   nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Segments at a time with an offset",
+			userID:         "1",
+			query:          "?at=2023-08-15T15:00:00%2B03:00",
+			errUsecaseAt:   nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Segments at a future time",
+			userID:         "1",
+			query:          "?at=2100-01-01T00:00:00Z",
+			errUsecaseAt:   entity.ErrFutureTime,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid time",
+			userID:         "1",
+			query:          "?at=2023-08-15",
+			errUsecaseAt:   nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Segments at a time of a non-existent user",
+			userID:         "1",
+			query:          "?at=2023-08-15T12:00:00Z",
+			errUsecaseAt:   entity.ErrUserNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
 	}
 
 	for _, tc := range testCase {
@@ -226,13 +576,178 @@ func TestUserSegments(t *testing.T) {
 			l:  logger,
 		}
 		mockUsecase.On("UserSegments", mock.Anything).Return([]entity.SlugWithExpiredDate{{}}, tc.errUsecase)
+		mockUsecase.On("UserSegmentsAt", mock.Anything, mock.Anything).Return([]entity.SlugWithExpiredDate{{}}, tc.errUsecaseAt)
 
 		mockContext.Params = []gin.Param{{Key: "user_id", Value: tc.userID}}
-		mockContext.Request = httptest.NewRequest("GET", "/users/"+tc.userID+"/segments", nil)
+		mockContext.Request = httptest.NewRequest("GET", "/users/"+tc.userID+"/segments"+tc.query, nil)
 		mockContext.Request.Header.Set("Accept", "application/json")
 
 		handler.userSegments(mockContext)
 		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+		if tc.query != "" {
+			mockUsecase.AssertNotCalled(t, "UserSegments", mock.Anything)
+		}
+	}
+}
+
+func TestUserSegmentsAtUnknownExpiration(t *testing.T) {
+	expiresAt := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	mockUsecase := new(mocks.UserUsecase)
+	w := httptest.NewRecorder()
+	mockContext, _ := gin.CreateTestContext(w)
+
+	handler := userHandler{
+		uc: mockUsecase,
+		l:  logger.New(),
+	}
+	mockUsecase.On("UserSegmentsAt", 1, mock.Anything).Return([]entity.SlugWithExpiredDate{
+		{Slug: "AVITO_DISCOUNT_30", ExpirationUnknown: true},
+		{Slug: "AVITO_DISCOUNT_50", ExpiredDate: expiresAt},
+	}, nil)
+
+	mockContext.Params = []gin.Param{{Key: "user_id", Value: "1"}}
+	mockContext.Request = httptest.NewRequest("GET", "/users/1/segments?at=2023-08-15T12:00:00Z", nil)
+
+	handler.userSegments(mockContext)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp []responseUserSegments
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp, 2)
+	require.True(t, resp[0].ExpirationUnknown)
+	require.Nil(t, resp[0].ExpiredDate)
+	require.False(t, resp[1].ExpirationUnknown)
+	require.True(t, expiresAt.Equal(*resp[1].ExpiredDate))
+}
+
+func TestUserSegmentsHistory(t *testing.T) {
+	expiresAt := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	history := []entity.UserSegmentsHistory{
+		{OperationID: 3, UserID: 1, SegmentSlug: "AVITO_DISCOUNT_30", Operation: entity.OperationAdded, ExpirationDate: &expiresAt},
+		{OperationID: 8, UserID: 1, SegmentSlug: "AVITO_DISCOUNT_30", Operation: entity.OperationExpired, ExpirationDate: &expiresAt},
+	}
+
+	testCase := []struct {
+		name           string
+		userID         string
+		query          string
+		history        []entity.UserSegmentsHistory
+		next           int
+		errUsecase     error
+		expectedStatus int
+		expectedLen    int
+		expectedNext   int
+	}{
+		{
+			name:           "Success test",
+			userID:         "1",
+			query:          "",
+			history:        history,
+			next:           0,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+			expectedLen:    2,
+			expectedNext:   0,
+		},
+		{
+			name:           "Page with a next one",
+			userID:         "1",
+			query:          "?after=2&limit=2",
+			history:        history,
+			next:           8,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+			expectedLen:    2,
+			expectedNext:   8,
+		},
+		{
+			name:           "Empty history",
+			userID:         "1",
+			query:          "?after=8",
+			history:        nil,
+			next:           0,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+			expectedLen:    0,
+			expectedNext:   0,
+		},
+		{
+			name:           "Invalid user",
+			userID:         "not_int",
+			query:          "",
+			history:        nil,
+			next:           0,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid limit",
+			userID:         "1",
+			query:          "?limit=1000",
+			history:        nil,
+			next:           0,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid cursor",
+			userID:         "1",
+			query:          "?after=abc",
+			history:        nil,
+			next:           0,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Non-existent user",
+			userID:         "0",
+			query:          "",
+			history:        nil,
+			next:           0,
+			errUsecase:     entity.ErrUserNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Usecase error",
+			userID:         "1",
+			query:          "",
+			history:        nil,
+			next:           0,
+			errUsecase:     errors.New("unexpected error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUsecase := new(mocks.UserUsecase)
+			w := httptest.NewRecorder()
+			mockContext, _ := gin.CreateTestContext(w)
+
+			handler := userHandler{
+				uc: mockUsecase,
+				l:  logger.New(),
+			}
+			mockUsecase.On("UserHistory", mock.Anything, mock.Anything, mock.Anything).Return(tc.history, tc.next, tc.errUsecase)
+
+			mockContext.Params = []gin.Param{{Key: "user_id", Value: tc.userID}}
+			mockContext.Request = httptest.NewRequest("GET", "/users/"+tc.userID+"/segments/history"+tc.query, nil)
+
+			handler.userSegmentsHistory(mockContext)
+			require.Equal(t, tc.expectedStatus, w.Code)
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp responseUserSegmentsHistory
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Len(t, resp.Operations, tc.expectedLen)
+			require.Equal(t, tc.expectedNext, resp.NextCursor)
+			if tc.expectedLen > 0 {
+				require.Equal(t, "expired", resp.Operations[1].Operation)
+				require.True(t, expiresAt.Equal(*resp.Operations[1].ExpirationDate))
+			}
+		})
 	}
 }
 
@@ -284,6 +799,70 @@ func TestUsersHistoryInCSVByDate(t *testing.T) {
 			errUsecase:     nil,
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name: "Date range with filters",
+			reqJSON: `
+				{
+					"from": "2023-08-01T00:00:00Z",
+					"to": "2023-08-15T00:00:00+03:00",
+					"user_id": 1,
+					"segment_slug": "AVITO_DISCOUNT_30",
+					"operations": ["added", "shortened", "expired"]
+				}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "Both month and date range",
+			reqJSON: `
+				{
+					"year": 2023,
+					"month": 8,
+					"from": "2023-08-01T00:00:00Z",
+					"to": "2023-08-15T00:00:00Z"
+				}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Missing to",
+			reqJSON: `
+				{
+					"from": "2023-08-01T00:00:00Z"
+				}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Missing month",
+			reqJSON: `
+				{
+					"year": 2023
+				}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Unknown operation",
+			reqJSON: `
+				{
+					"from": "2023-08-01T00:00:00Z",
+					"to": "2023-08-15T00:00:00Z",
+					"operations": ["renamed"]
+				}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "From after to",
+			reqJSON: `
+				{
+					"from": "2023-08-15T00:00:00Z",
+					"to": "2023-08-01T00:00:00Z"
+				}`,
+			errUsecase:     entity.ErrInvalidHistoryFilter,
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tc := range testCase {
@@ -295,7 +874,7 @@ func TestUsersHistoryInCSVByDate(t *testing.T) {
 			uc: mockUsecase,
 			l:  logger,
 		}
-		mockUsecase.On("UsersHistoryInCSVByDate", mock.Anything, mock.Anything).Return("link", tc.errUsecase)
+		mockUsecase.On("UsersHistoryInCSV", mock.Anything).Return("link", tc.errUsecase)
 
 		mockContext.Request = httptest.NewRequest("POST", "/users/segments/history", strings.NewReader(tc.reqJSON))
 		mockContext.Request.Header.Set("Accept", "application/json")
@@ -305,6 +884,111 @@ func TestUsersHistoryInCSVByDate(t *testing.T) {
 	}
 }
 
+func TestUserAttributes(t *testing.T) {
+	testCase := []struct {
+		name           string
+		userID         string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success test",
+			userID:         "1",
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Non-existent user",
+			userID:         "1",
+			errUsecase:     entity.ErrUserNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Invalid user id",
+			userID:         "1invalid",
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCase {
+		logger := logger.New()
+		mockUsecase := new(mocks.UserUsecase)
+		mockContext := newMockGinContext()
+
+		handler := userHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("UserAttributes", mock.Anything).Return(map[string]any{"country": "RU"}, tc.errUsecase)
+
+		mockContext.Params = []gin.Param{{Key: "user_id", Value: tc.userID}}
+		mockContext.Request = httptest.NewRequest("GET", "/users/"+tc.userID+"/attributes", nil)
+		mockContext.Request.Header.Set("Accept", "application/json")
+
+		handler.userAttributes(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
+func TestSetUserAttributes(t *testing.T) {
+	testCase := []struct {
+		name           string
+		userID         string
+		reqJSON        string
+		errUsecase     error
+		expectedStatus int
+	}{
+		{
+			name:           "Success test",
+			userID:         "1",
+			reqJSON:        `{"country": "RU", "platform": "ios", "age": 27}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Non-existent user",
+			userID:         "1",
+			reqJSON:        `{"country": "RU"}`,
+			errUsecase:     entity.ErrUserNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Invalid json",
+			userID:         "1",
+			reqJSON:        `["RU"]`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid user id",
+			userID:         "1invalid",
+			reqJSON:        `{"country": "RU"}`,
+			errUsecase:     nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCase {
+		logger := logger.New()
+		mockUsecase := new(mocks.UserUsecase)
+		mockContext := newMockGinContext()
+
+		handler := userHandler{
+			uc: mockUsecase,
+			l:  logger,
+		}
+		mockUsecase.On("SetUserAttributes", mock.Anything, mock.Anything).Return(tc.errUsecase)
+
+		mockContext.Params = []gin.Param{{Key: "user_id", Value: tc.userID}}
+		mockContext.Request = httptest.NewRequest("PUT", "/users/"+tc.userID+"/attributes", strings.NewReader(tc.reqJSON))
+		mockContext.Request.Header.Set("Content-Type", "application/json")
+
+		handler.setUserAttributes(mockContext)
+		require.Equal(t, tc.expectedStatus, mockContext.Writer.Status())
+	}
+}
+
 func newMockGinContext() *gin.Context {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"experiment.io/internal/entity"
+	"experiment.io/pkg/duration"
 	"experiment.io/pkg/logger"
 	"github.com/gin-gonic/gin"
 )
@@ -18,9 +21,14 @@ type userHandler struct {
 
 type UserUsecase interface {
 	UserSegments(userID int) ([]entity.SlugWithExpiredDate, error)
+	UserSegmentsAt(userID int, at time.Time) ([]entity.SlugWithExpiredDate, error)
 	AddUserSegments(userID int, added []entity.SlugWithExpiredDate) error
 	RemoveUserSegments(userID int, removed []string) error
-	UsersHistoryInCSVByDate(year int, month int) (string, error)
+	UpdateUserSegments(userID int, updated []entity.SlugWithExpiredDate) error
+	UsersHistoryInCSV(filter entity.HistoryFilter) (string, error)
+	UserHistory(userID int, afterID int, limit int) ([]entity.UserSegmentsHistory, int, error)
+	UserAttributes(userID int) (map[string]any, error)
+	SetUserAttributes(userID int, attributes map[string]any) error
 }
 
 func NewUserHandler(route *gin.RouterGroup, l *logger.Logger, uc UserUsecase) {
@@ -29,18 +37,62 @@ func NewUserHandler(route *gin.RouterGroup, l *logger.Logger, uc UserUsecase) {
 		route.POST("/users/segments/history", h.createUsersHistoryInCSVByDate)
 		route.PATCH("/users/:user_id/segments", h.editUserSegments)
 		route.GET("/users/:user_id/segments", h.userSegments)
+		route.GET("/users/:user_id/segments/history", h.userSegmentsHistory)
+		route.GET("/users/:user_id/attributes", h.userAttributes)
+		route.PUT("/users/:user_id/attributes", h.setUserAttributes)
 	}
 }
 
-// added segments will be ignored after ttl expires
+// added segments will be ignored after ttl expires,
+// updated segments get the new expiration and are added when the user is not a member yet
 type requestEditUserSegments struct {
 	AddSegments    []AddSegments `json:"add_segments" binding:"max=100"`
+	UpdateSegments []AddSegments `json:"update_segments" binding:"max=100"`
 	RemoveSegments []string      `json:"remove_segments" binding:"max=100"`
 }
 
+// ttl and expires_at are mutually exclusive, see Validator
 type AddSegments struct {
-	Slug string `json:"slug" binding:"required,max=100"`
-	TTL  int    `json:"ttl" binding:"min=0,max=100"`
+	Slug      string     `json:"slug" binding:"required,max=100"`
+	TTL       *TTL       `json:"ttl"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (s AddSegments) expiredDate() time.Time {
+	if s.ExpiresAt != nil {
+		return *s.ExpiresAt
+	}
+	var ttl time.Duration
+	if s.TTL != nil {
+		ttl = time.Duration(*s.TTL)
+	}
+	return time.Now().Add(ttl)
+}
+
+// TTL is either an integer number of days or an ISO 8601 duration, e.g. "PT6H"
+type TTL time.Duration
+
+func (t *TTL) UnmarshalJSON(data []byte) error {
+	var days int
+	if err := json.Unmarshal(data, &days); err == nil {
+		// checked before converting, a large number of days overflows time.Duration
+		if days < 0 || days > int(maxTTL/(24*time.Hour)) {
+			return fmt.Errorf("ttl must be between 0 and %d days", int(maxTTL/(24*time.Hour)))
+		}
+		*t = TTL(time.Duration(days) * 24 * time.Hour)
+		return nil
+	}
+
+	var iso string
+	if err := json.Unmarshal(data, &iso); err != nil {
+		return fmt.Errorf("ttl must be a number of days or an ISO 8601 duration: %w", err)
+	}
+	d, err := duration.Parse(iso)
+	if err != nil {
+		return err
+	}
+	*t = TTL(d)
+	return nil
 }
 
 func (h *userHandler) editUserSegments(c *gin.Context) {
@@ -60,18 +112,23 @@ func (h *userHandler) editUserSegments(c *gin.Context) {
 	}
 
 	validator := NewValidator()
-	if !validator.checkAddedSegmentsIsValid(req.AddSegments) {
+	if !validator.checkAddedSegmentsIsValid(req.AddSegments) || !validator.checkAddedSegmentsIsValid(req.UpdateSegments) {
 		h.l.Error(entity.ErrInvalidAddedSegment)
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrInvalidAddedSegment.Error()})
 		return
 	}
 
-	// check whether added and removed segments intersect
+	// check whether added, updated and removed segments intersect
 	added := make([]string, len(req.AddSegments))
 	for i := range req.AddSegments {
 		added[i] = req.AddSegments[i].Slug
 	}
-	if validator.IsIntersect(added, req.RemoveSegments) {
+	updated := make([]string, len(req.UpdateSegments))
+	for i := range req.UpdateSegments {
+		updated[i] = req.UpdateSegments[i].Slug
+	}
+	if validator.IsIntersect(added, req.RemoveSegments) || validator.IsIntersect(updated, req.RemoveSegments) ||
+		validator.IsIntersect(added, updated) {
 		h.l.Error(err)
 		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrSegmentsIntersect.Error()})
 		return
@@ -84,6 +141,10 @@ func (h *userHandler) editUserSegments(c *gin.Context) {
 				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"msg:": entity.ErrUserToSegmentNotFound.Error()})
 				return
 			}
+			if errors.Is(err, entity.ErrPrerequisiteRequired) {
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"msg:": entity.ErrPrerequisiteRequired.Error()})
+				return
+			}
 			c.AbortWithStatus(http.StatusInternalServerError)
 			return
 		}
@@ -93,26 +154,30 @@ func (h *userHandler) editUserSegments(c *gin.Context) {
 	for i, reqSeg := range req.AddSegments {
 		addedSlugWithTTL[i] = entity.SlugWithExpiredDate{
 			Slug:        reqSeg.Slug,
-			ExpiredDate: time.Now().Add(time.Duration(reqSeg.TTL) * 24 * time.Hour),
+			ExpiredDate: reqSeg.expiredDate(),
 		}
 	}
 
 	if len(added) > 0 {
 		if err := h.uc.AddUserSegments(id, addedSlugWithTTL); err != nil {
 			h.l.Error(err)
-			status := http.StatusInternalServerError
-			respErr := entity.ErrInternalServer
-			switch {
-			case errors.Is(err, entity.ErrUserNotFound):
-				status = http.StatusNotFound
-				respErr = entity.ErrUserNotFound
-			case errors.Is(err, entity.ErrSegmentNotFound):
-				status = http.StatusUnprocessableEntity
-				respErr = entity.ErrSegmentNotFound
-			case errors.Is(err, entity.ErrUserAlreadyAssigned):
-				status = http.StatusConflict
-				respErr = entity.ErrUserAlreadyAssigned
+			status, respErr := addedSegmentsError(err)
+			c.AbortWithStatusJSON(status, gin.H{"msg:": respErr.Error()})
+			return
+		}
+	}
+
+	if len(updated) > 0 {
+		updatedSlugWithTTL := make([]entity.SlugWithExpiredDate, len(req.UpdateSegments))
+		for i, reqSeg := range req.UpdateSegments {
+			updatedSlugWithTTL[i] = entity.SlugWithExpiredDate{
+				Slug:        reqSeg.Slug,
+				ExpiredDate: reqSeg.expiredDate(),
 			}
+		}
+		if err := h.uc.UpdateUserSegments(id, updatedSlugWithTTL); err != nil {
+			h.l.Error(err)
+			status, respErr := addedSegmentsError(err)
 			c.AbortWithStatusJSON(status, gin.H{"msg:": respErr.Error()})
 			return
 		}
@@ -121,9 +186,46 @@ func (h *userHandler) editUserSegments(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+// Maps errors of adding the user to segments to the response status and message
+func addedSegmentsError(err error) (int, error) {
+	switch {
+	case errors.Is(err, entity.ErrUserNotFound):
+		return http.StatusNotFound, entity.ErrUserNotFound
+	case errors.Is(err, entity.ErrSegmentNotFound):
+		return http.StatusUnprocessableEntity, entity.ErrSegmentNotFound
+	case errors.Is(err, entity.ErrUserAlreadyAssigned):
+		return http.StatusConflict, entity.ErrUserAlreadyAssigned
+	case errors.Is(err, entity.ErrVariantConflict):
+		return http.StatusConflict, entity.ErrVariantConflict
+	case errors.Is(err, entity.ErrLayerConflict):
+		return http.StatusConflict, entity.ErrLayerConflict
+	case errors.Is(err, entity.ErrUserInHoldout):
+		return http.StatusConflict, entity.ErrUserInHoldout
+	case errors.Is(err, entity.ErrSegmentArchived):
+		return http.StatusUnprocessableEntity, entity.ErrSegmentArchived
+	case errors.Is(err, entity.ErrPrerequisiteMissing):
+		return http.StatusUnprocessableEntity, entity.ErrPrerequisiteMissing
+	case errors.Is(err, entity.ErrSegmentFull):
+		return http.StatusForbidden, entity.ErrSegmentFull
+	case errors.Is(err, entity.ErrPrerequisiteRequired):
+		return http.StatusConflict, entity.ErrPrerequisiteRequired
+	}
+	return http.StatusInternalServerError, entity.ErrInternalServer
+}
+
+// with at the memberships at that time are reconstructed from the operations history
+type requestUserSegments struct {
+	At *time.Time `form:"at" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// expired_date is omitted when the expiration of a past membership is unknown
 type responseUserSegments struct {
-	Slug        string    `json:"slug"`
-	ExpiredDate time.Time `json:"expired_date"`
+	Slug              string     `json:"slug"`
+	ExpiredDate       *time.Time `json:"expired_date,omitempty"`
+	ExpirationUnknown bool       `json:"expiration_unknown,omitempty"`
+	Experiment        string     `json:"experiment,omitempty"`
+	Variant           string     `json:"variant,omitempty"`
+	Targeted          bool       `json:"targeted,omitempty"`
 }
 
 func (h *userHandler) userSegments(c *gin.Context) {
@@ -135,13 +237,29 @@ func (h *userHandler) userSegments(c *gin.Context) {
 		return
 	}
 
-	segments, err := h.uc.UserSegments(id)
+	var req requestUserSegments
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.l.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": err.Error()})
+		return
+	}
+
+	var segments []entity.SlugWithExpiredDate
+	if req.At != nil {
+		segments, err = h.uc.UserSegmentsAt(id, *req.At)
+	} else {
+		segments, err = h.uc.UserSegments(id)
+	}
 	if err != nil {
 		h.l.Error(err)
 		if errors.Is(err, entity.ErrUserNotFound) {
 			c.AbortWithStatus(http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, entity.ErrFutureTime) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrFutureTime.Error()})
+			return
+		}
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
@@ -149,15 +267,115 @@ func (h *userHandler) userSegments(c *gin.Context) {
 	resp := make([]responseUserSegments, len(segments))
 	for i, seg := range segments {
 		resp[i].Slug = seg.Slug
-		resp[i].ExpiredDate = seg.ExpiredDate
+		if !seg.ExpirationUnknown {
+			expiredDate := seg.ExpiredDate
+			resp[i].ExpiredDate = &expiredDate
+		}
+		resp[i].ExpirationUnknown = seg.ExpirationUnknown
+		resp[i].Experiment = seg.Experiment
+		resp[i].Variant = seg.Variant
+		resp[i].Targeted = seg.Targeted
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// after is the operation_id cursor returned as next_cursor by the previous page
+type requestUserSegmentsHistory struct {
+	After int `form:"after" binding:"omitempty,min=0"`
+	Limit int `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+type responseUserOperation struct {
+	OperationID    int        `json:"operation_id"`
+	SegmentSlug    string     `json:"segment_slug"`
+	Operation      string     `json:"operation"`
+	Date           time.Time  `json:"date"`
+	ExpirationDate *time.Time `json:"expiration_date,omitempty"`
+}
+
+type responseUserSegmentsHistory struct {
+	Operations []responseUserOperation `json:"operations"`
+	NextCursor int                     `json:"next_cursor,omitempty"`
+}
+
+func (h *userHandler) userSegmentsHistory(c *gin.Context) {
+	userID := c.Param("user_id")
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		h.l.Error(err)
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var req requestUserSegmentsHistory
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.l.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": err.Error()})
+		return
+	}
+
+	history, next, err := h.uc.UserHistory(id, req.After, req.Limit)
+	if err != nil {
+		h.l.Error(err)
+		if errors.Is(err, entity.ErrUserNotFound) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	resp := responseUserSegmentsHistory{
+		Operations: make([]responseUserOperation, len(history)),
+		NextCursor: next,
+	}
+	for i, hist := range history {
+		resp.Operations[i] = responseUserOperation{
+			OperationID:    hist.OperationID,
+			SegmentSlug:    hist.SegmentSlug,
+			Operation:      string(hist.Operation),
+			Date:           hist.Date,
+			ExpirationDate: hist.ExpirationDate,
+		}
 	}
 
 	c.JSON(http.StatusOK, resp)
 }
 
+// either a calendar month in UTC or the half-open range [from, to) can be given
 type requestHistoryInCSVByDate struct {
-	Year  int `json:"year" binding:"required,numeric,min=2007,max=2100"`
-	Month int `json:"month" binding:"required,numeric,min=1,max=12"`
+	Year        int        `json:"year" binding:"omitempty,numeric,min=2007,max=2100"`
+	Month       int        `json:"month" binding:"omitempty,numeric,min=1,max=12"`
+	From        *time.Time `json:"from"`
+	To          *time.Time `json:"to"`
+	UserID      *int       `json:"user_id" binding:"omitempty,min=1"`
+	SegmentSlug string     `json:"segment_slug" binding:"max=100"`
+	Operations  []string   `json:"operations" binding:"max=5,dive,oneof=added removed extended shortened expired"`
+}
+
+func (r requestHistoryInCSVByDate) toEntity() (entity.HistoryFilter, bool) {
+	filter := entity.HistoryFilter{
+		UserID:      r.UserID,
+		SegmentSlug: r.SegmentSlug,
+	}
+	for _, operation := range r.Operations {
+		filter.Operations = append(filter.Operations, entity.UserOperation(operation))
+	}
+
+	byMonth := r.Year != 0 || r.Month != 0
+	byRange := r.From != nil || r.To != nil
+	switch {
+	case byMonth && !byRange && r.Year != 0 && r.Month != 0:
+		filter.From = time.Date(r.Year, time.Month(r.Month), 1, 0, 0, 0, 0, time.UTC)
+		filter.To = filter.From.AddDate(0, 1, 0)
+	case byRange && !byMonth && r.From != nil && r.To != nil:
+		filter.From = *r.From
+		filter.To = *r.To
+	default:
+		return entity.HistoryFilter{}, false
+	}
+	return filter, true
 }
 
 type responseHistoryInCSVByDate struct {
@@ -172,9 +390,20 @@ func (h *userHandler) createUsersHistoryInCSVByDate(c *gin.Context) {
 		return
 	}
 
-	path, err := h.uc.UsersHistoryInCSVByDate(req.Year, req.Month)
+	filter, ok := req.toEntity()
+	if !ok {
+		h.l.Error(entity.ErrInvalidHistoryFilter)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrInvalidHistoryFilter.Error()})
+		return
+	}
+
+	path, err := h.uc.UsersHistoryInCSV(filter)
 	if err != nil {
 		h.l.Error(err)
+		if errors.Is(err, entity.ErrInvalidHistoryFilter) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": entity.ErrInvalidHistoryFilter.Error()})
+			return
+		}
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
@@ -183,3 +412,55 @@ func (h *userHandler) createUsersHistoryInCSVByDate(c *gin.Context) {
 		Link: path,
 	})
 }
+
+func (h *userHandler) userAttributes(c *gin.Context) {
+	userID := c.Param("user_id")
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		h.l.Error(err)
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	attributes, err := h.uc.UserAttributes(id)
+	if err != nil {
+		h.l.Error(err)
+		if errors.Is(err, entity.ErrUserNotFound) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, attributes)
+}
+
+func (h *userHandler) setUserAttributes(c *gin.Context) {
+	userID := c.Param("user_id")
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		h.l.Error(err)
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var req map[string]any
+	if err := c.BindJSON(&req); err != nil {
+		h.l.Error(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"msg:": err.Error()})
+		return
+	}
+
+	if err := h.uc.SetUserAttributes(id, req); err != nil {
+		h.l.Error(err)
+		if errors.Is(err, entity.ErrUserNotFound) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -1,5 +1,9 @@
 package handlers
 
+import "time"
+
+const maxTTL = 366 * 24 * time.Hour
+
 type Validator struct {
 }
 
@@ -23,12 +27,23 @@ func (v *Validator) IsIntersect(a, b []string) bool {
 	return false
 }
 
+// ttl and expires_at are mutually exclusive, ttl is at most 366 days and expires_at must be in the future
+func (v *Validator) checkExpirationIsValid(ttl *TTL, expiresAt *time.Time) bool {
+	if ttl != nil && expiresAt != nil {
+		return false
+	}
+	if ttl != nil && (*ttl < 0 || time.Duration(*ttl) > maxTTL) {
+		return false
+	}
+	return expiresAt == nil || expiresAt.After(time.Now())
+}
+
 func (v *Validator) checkAddedSegmentsIsValid(segments []AddSegments) bool {
 	for _, seg := range segments {
 		if seg.Slug == "" {
 			return false
 		}
-		if seg.TTL < 0 || seg.TTL > 366 {
+		if !v.checkExpirationIsValid(seg.TTL, seg.ExpiresAt) {
 			return false
 		}
 	}
@@ -8,12 +8,19 @@ import (
 )
 
 func SetupRouter(g *gin.Engine, l *logger.Logger, segmentUC *usecase.SegmentUsecase, userUC *usecase.UserUsecase, authUC *usecase.AuthUsecase,
-	authMiddleware gin.HandlerFunc) {
+	experimentUC *usecase.ExperimentUsecase, expiryUC *usecase.ExpiryUsecase, authMiddleware gin.HandlerFunc) {
 	router := g.Group("/api/v1")
 	{
 		handlers.NewSegmentHandler(router, l, segmentUC)
 		handlers.NewUserHandler(router, l, userUC)
 		handlers.NewAuthHandler(router, l, authUC)
+		handlers.NewExperimentHandler(router, l, experimentUC)
+	}
+
+	admin := g.Group("/api/v1/admin", authMiddleware)
+	{
+		handlers.NewSegmentAdminHandler(admin, l, segmentUC)
+		handlers.NewExpiryAdminHandler(admin, l, expiryUC)
 	}
 
 	static := g.Group("/history", authMiddleware)
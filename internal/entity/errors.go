@@ -3,16 +3,40 @@ package entity
 import "errors"
 
 var (
-	ErrUserNotFound          = errors.New("user not found")
-	ErrInternalServer        = errors.New("internal server error")
-	ErrSegmentNotFound       = errors.New("segment not found")
-	ErrInvalidPassString     = errors.New("invalid password string") // if it is not possible to hash the password
-	ErrInvalidNameOrPass     = errors.New("invalid username or password")
-	ErrInvalidToken          = errors.New("invalid or unspecified token")
-	ErrUserAlreadyExist      = errors.New("user already exist")
-	ErrInvalidAddedSegment   = errors.New("add_segments: ttl must be less or equal 366, greater or equal 0. slug must be provided")
-	ErrSegmentAlreadyExist   = errors.New("segment already exist")
-	ErrSegmentsIntersect     = errors.New("added and removed segments intersect")
-	ErrUserAlreadyAssigned   = errors.New("the user is already assigned this segment")
-	ErrUserToSegmentNotFound = errors.New("the user is not assigned this segment")
+	ErrUserNotFound           = errors.New("user not found")
+	ErrInternalServer         = errors.New("internal server error")
+	ErrSegmentNotFound        = errors.New("segment not found")
+	ErrInvalidPassString      = errors.New("invalid password string") // if it is not possible to hash the password
+	ErrInvalidNameOrPass      = errors.New("invalid username or password")
+	ErrInvalidToken           = errors.New("invalid or unspecified token")
+	ErrUserAlreadyExist       = errors.New("user already exist")
+	ErrInvalidAddedSegment    = errors.New("add_segments: ttl must be less or equal 366 days, greater or equal 0. expires_at must be in the future and can not be combined with ttl. slug must be provided")
+	ErrSegmentAlreadyExist    = errors.New("segment already exist")
+	ErrSegmentsIntersect      = errors.New("added and removed segments intersect")
+	ErrUserAlreadyAssigned    = errors.New("the user is already assigned this segment")
+	ErrUserToSegmentNotFound  = errors.New("the user is not assigned this segment")
+	ErrExperimentAlreadyExist = errors.New("experiment already exist")
+	ErrExperimentNotFound     = errors.New("experiment not found")
+	ErrInvalidExperiment      = errors.New("experiment must have at least two variants with unique names and positive weights")
+	ErrVariantConflict        = errors.New("the user is already assigned another variant of the experiment")
+	ErrLayerConflict          = errors.New("the user is already assigned another segment of the layer")
+	ErrInvalidRule            = errors.New("invalid targeting rule")
+	ErrSegmentArchived        = errors.New("segment is archived")
+	ErrSegmentNotArchived     = errors.New("segment is not archived")
+	ErrNotRolloutSegment      = errors.New("the segment is not a percentage rollout segment")
+	ErrInvalidSegmentWindow   = errors.New("segment starts_at must be before ends_at")
+	ErrUserInHoldout          = errors.New("the user is in the holdout and the segment does not allow it")
+	ErrInvalidHoldout         = errors.New("holdout percent must be between 0 and 100")
+	ErrPrerequisiteNotFound   = errors.New("prerequisite segment not found")
+	ErrPrerequisiteCycle      = errors.New("segment prerequisites form a cycle")
+	ErrPrerequisiteMissing    = errors.New("the user is not assigned a prerequisite of the segment")
+	ErrPrerequisiteRequired   = errors.New("the segment is a prerequisite of another segment of the user")
+	ErrSegmentFull            = errors.New("the segment has reached its maximum number of members")
+	ErrInvalidMaxMembers      = errors.New("max members must be positive")
+	ErrInvalidExpression      = errors.New("invalid segment expression")
+	ErrInvalidExpiration      = errors.New("either ttl up to 366 days or expires_at in the future can be provided")
+	ErrInvalidRolloutSchedule = errors.New("rollout steps must have a percent between 0 and 100 and distinct apply_at")
+	ErrNotificationRejected   = errors.New("the webhook responded with a non-2xx status")
+	ErrFutureTime             = errors.New("the time must not be in the future")
+	ErrInvalidHistoryFilter   = errors.New("either year and month or from before to must be provided, operations can be added, removed, extended, shortened or expired")
 )
@@ -0,0 +1,17 @@
+package entity
+
+// Experiment splits all users between its variants proportionally to their weights,
+// so every user is assigned exactly one variant
+type Experiment struct {
+	Slug     string
+	Salt     string
+	Layer    string
+	Variants []Variant
+}
+
+type Variant struct {
+	Name    string
+	Weight  int
+	Segment string // slug of the segment backing the variant
+	Members int
+}
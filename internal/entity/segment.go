@@ -2,11 +2,155 @@ package entity
 
 import "time"
 
+// Expiration date of memberships without ttl
+var MaxTime = time.Date(9999, time.December, 31, 23, 59, 59, 999999999, time.UTC)
+
+// Defines how users are picked during auto-assignment
+type AssignMode string
+
+const (
+	AssignModeRandom AssignMode = "random"
+	AssignModeHash   AssignMode = "hash" // stable assignment by bucket of (salt, user id)
+)
+
+// Defines what happens to a membership when the user loses a prerequisite of the segment
+type RemovalPolicy string
+
+const (
+	RemovalPolicyBlock   RemovalPolicy = "block"   // the prerequisite can not be removed
+	RemovalPolicyCascade RemovalPolicy = "cascade" // the membership is removed along with the prerequisite
+)
+
 type Segment struct {
+	Slug       string
+	Salt       string
+	AssignMode AssignMode
+	Layer      string // a user can be in at most one segment of a layer
+	Rule       string // targeting rule over user attributes, see pkg/rules
+
+	Experiment       string // set when the segment is a variant of an experiment
+	Variant          string
+	RolloutPercent   *int       // set when users are auto-assigned to the segment
+	RolloutExpiresAt *time.Time // memberships added by the rollout expire at this time, nil means never
+
+	Description string
+	Owner       string
+	Tags        []string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	ArchivedAt  *time.Time // archived segments keep their memberships but are not returned to users
+
+	// the segment is returned to users only within [StartsAt, EndsAt), nil bounds are open
+	StartsAt *time.Time
+	EndsAt   *time.Time
+
+	AllowHoldout bool // holdout users can be enrolled in the segment
+
+	Prerequisites []string // segments a user must be an active member of to be assigned this one
+	RemovalPolicy RemovalPolicy
+
+	MaxMembers *int // maximum number of active members, nil means unlimited
+}
+
+// Editable segment metadata, nil fields are left unchanged
+type SegmentUpdate struct {
+	Description *string
+	Owner       *string
+	Tags        []string
+	StartsAt    *time.Time
+	EndsAt      *time.Time
+	// removes the activation window, can not be combined with StartsAt and EndsAt
+	ClearWindow   bool
+	AllowHoldout  *bool
+	Prerequisites []string
+	RemovalPolicy *RemovalPolicy
+	MaxMembers    *int
+	// makes the segment unlimited, can not be combined with MaxMembers
+	ClearMaxMembers bool
+}
+
+// Change of segment columns recorded by the audit trail
+type SegmentChange struct {
+	ID          int
+	SegmentSlug string
+	OldValues   map[string]any
+	NewValues   map[string]any
+	Date        time.Time
+}
+
+// Stable share of users that are never enrolled in segments unless a segment allows it
+type Holdout struct {
+	Salt    string
+	Percent int
+}
+
+// Users added to and removed from a segment by changing its rollout percent
+type RolloutChange struct {
+	Added   []int
+	Removed []int
+}
+
+// Planned change of segment rollout percent
+type RolloutStep struct {
+	ID          int
+	SegmentSlug string
+	Percent     int
+	ApplyAt     time.Time
+	AppliedAt   *time.Time // set once the scheduler has applied the step
+	Attempts    int        // failed attempts to apply the step
+	LastError   string     // error of the last failed attempt
+}
+
+// Copy of a segment under a new slug
+type SegmentClone struct {
+	Slug            string
+	CopyMembers     bool // copy active (non-expired) memberships of the source segment
+	ResetExpiration bool // copied memberships never expire instead of keeping the source expiration
+}
+
+type SetOperation string
+
+const (
+	SetUnion        SetOperation = "union"
+	SetIntersection SetOperation = "intersect"
+	SetDifference   SetOperation = "difference" // members of the first argument not in any other
+)
+
+// Set expression over segment memberships: either a segment slug
+// or an operation over at least two nested expressions
+type SegmentExpression struct {
 	Slug string
+	Op   SetOperation
+	Args []SegmentExpression
+}
+
+// Segment with the number of its active (non-expired) members
+type SegmentInfo struct {
+	Segment
+	Members int
+}
+
+type SegmentSort string
+
+const (
+	SegmentSortSlug    SegmentSort = "slug"
+	SegmentSortMembers SegmentSort = "members"
+)
+
+type SegmentFilter struct {
+	Prefix   string // slug prefix
+	Archived bool   // list archived segments instead of active ones
+	Sort     SegmentSort
+	Desc     bool
+	Limit    int
+	Offset   int
 }
 
 type SlugWithExpiredDate struct {
-	Slug string
-	ExpiredDate  time.Time
+	Slug              string
+	ExpiredDate       time.Time
+	Experiment        string // set when the segment is a variant of an experiment
+	Variant           string
+	Targeted          bool // the user is a member because his attributes match the segment rule
+	ExpirationUnknown bool // the membership was added before expirations were recorded, it may have expired by then
 }
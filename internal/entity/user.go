@@ -3,8 +3,28 @@ package entity
 import "time"
 
 type User struct {
-	Name     string
-	Password string
+	Name       string
+	Password   string
+	Attributes map[string]any // used by segment targeting rules
+}
+
+type UserOperation string
+
+const (
+	OperationAdded     UserOperation = "added"
+	OperationRemoved   UserOperation = "removed"
+	OperationExtended  UserOperation = "extended"  // the membership was given a later expiration
+	OperationShortened UserOperation = "shortened" // the membership was given an earlier expiration
+	OperationExpired   UserOperation = "expired"   // the expired membership was removed, dated by its expiration
+)
+
+// Operations within the half-open range [From, To), empty filters match everything
+type HistoryFilter struct {
+	From        time.Time
+	To          time.Time
+	UserID      *int
+	SegmentSlug string
+	Operations  []UserOperation
 }
 
 type UserSegmentsHistory struct {
@@ -12,5 +32,24 @@ type UserSegmentsHistory struct {
 	UserID      int
 	SegmentSlug string
 	IsAdded     bool
+	Operation   UserOperation
 	Date        time.Time
+	// expiration of the membership after the operation, nil for operations recorded before it was tracked
+	ExpirationDate *time.Time
+}
+
+// Last run of the removal of expired memberships
+type ExpiryRun struct {
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	Removed      int // memberships removed by the run
+	TotalRemoved int // memberships removed since the service start
+	Err          error
+}
+
+// Membership expiring soon that the notifier is told about
+type ExpiryNotification struct {
+	SegmentSlug string
+	UserID      int
+	ExpiredDate time.Time
 }
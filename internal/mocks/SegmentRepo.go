@@ -5,6 +5,8 @@ package mocks
 import (
 	entity "experiment.io/internal/entity"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // SegmentRepo is an autogenerated mock type for the SegmentRepo type
@@ -12,8 +14,39 @@ type SegmentRepo struct {
 	mock.Mock
 }
 
-// DeleteSegment provides a mock function with given fields: slug
-func (_m *SegmentRepo) DeleteSegment(slug string) error {
+// ApplyNextRolloutStep provides a mock function with given fields: skipped
+func (_m *SegmentRepo) ApplyNextRolloutStep(skipped []int) (entity.RolloutStep, bool, error) {
+	ret := _m.Called(skipped)
+
+	var r0 entity.RolloutStep
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func([]int) (entity.RolloutStep, bool, error)); ok {
+		return rf(skipped)
+	}
+	if rf, ok := ret.Get(0).(func([]int) entity.RolloutStep); ok {
+		r0 = rf(skipped)
+	} else {
+		r0 = ret.Get(0).(entity.RolloutStep)
+	}
+
+	if rf, ok := ret.Get(1).(func([]int) bool); ok {
+		r1 = rf(skipped)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func([]int) error); ok {
+		r2 = rf(skipped)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ArchiveSegment provides a mock function with given fields: slug
+func (_m *SegmentRepo) ArchiveSegment(slug string) error {
 	ret := _m.Called(slug)
 
 	var r0 error
@@ -26,6 +59,106 @@ func (_m *SegmentRepo) DeleteSegment(slug string) error {
 	return r0
 }
 
+// AutoAssignSegment provides a mock function with given fields: slug, percent, expiresAt
+func (_m *SegmentRepo) AutoAssignSegment(slug string, percent int, expiresAt *time.Time) ([]int, error) {
+	ret := _m.Called(slug, percent, expiresAt)
+
+	var r0 []int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int, *time.Time) ([]int, error)); ok {
+		return rf(slug, percent, expiresAt)
+	}
+	if rf, ok := ret.Get(0).(func(string, int, *time.Time) []int); ok {
+		r0 = rf(slug, percent, expiresAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int, *time.Time) error); ok {
+		r1 = rf(slug, percent, expiresAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BackfillRollouts provides a mock function with given fields:
+func (_m *SegmentRepo) BackfillRollouts() (int, error) {
+	ret := _m.Called()
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (int, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CloneSegment provides a mock function with given fields: slug, clone
+func (_m *SegmentRepo) CloneSegment(slug string, clone entity.SegmentClone) ([]int, error) {
+	ret := _m.Called(slug, clone)
+
+	var r0 []int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, entity.SegmentClone) ([]int, error)); ok {
+		return rf(slug, clone)
+	}
+	if rf, ok := ret.Get(0).(func(string, entity.SegmentClone) []int); ok {
+		r0 = rf(slug, clone)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, entity.SegmentClone) error); ok {
+		r1 = rf(slug, clone)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Holdout provides a mock function with given fields:
+func (_m *SegmentRepo) Holdout() (entity.Holdout, error) {
+	ret := _m.Called()
+
+	var r0 entity.Holdout
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (entity.Holdout, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() entity.Holdout); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(entity.Holdout)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewSegment provides a mock function with given fields: seg
 func (_m *SegmentRepo) NewSegment(seg entity.Segment) error {
 	ret := _m.Called(seg)
@@ -40,6 +173,32 @@ func (_m *SegmentRepo) NewSegment(seg entity.Segment) error {
 	return r0
 }
 
+// NewSegmentFromExpression provides a mock function with given fields: seg, expr
+func (_m *SegmentRepo) NewSegmentFromExpression(seg entity.Segment, expr entity.SegmentExpression) ([]int, error) {
+	ret := _m.Called(seg, expr)
+
+	var r0 []int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.Segment, entity.SegmentExpression) ([]int, error)); ok {
+		return rf(seg, expr)
+	}
+	if rf, ok := ret.Get(0).(func(entity.Segment, entity.SegmentExpression) []int); ok {
+		r0 = rf(seg, expr)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.Segment, entity.SegmentExpression) error); ok {
+		r1 = rf(seg, expr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewSegmentWithAutoAssign provides a mock function with given fields: seg, percentAssigned
 func (_m *SegmentRepo) NewSegmentWithAutoAssign(seg entity.Segment, percentAssigned int) ([]int, error) {
 	ret := _m.Called(seg, percentAssigned)
@@ -66,6 +225,219 @@ func (_m *SegmentRepo) NewSegmentWithAutoAssign(seg entity.Segment, percentAssig
 	return r0, r1
 }
 
+// PurgeSegment provides a mock function with given fields: slug
+func (_m *SegmentRepo) PurgeSegment(slug string) error {
+	ret := _m.Called(slug)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(slug)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RestoreSegment provides a mock function with given fields: slug
+func (_m *SegmentRepo) RestoreSegment(slug string) error {
+	ret := _m.Called(slug)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(slug)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RolloutSchedule provides a mock function with given fields: slug
+func (_m *SegmentRepo) RolloutSchedule(slug string) ([]entity.RolloutStep, error) {
+	ret := _m.Called(slug)
+
+	var r0 []entity.RolloutStep
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]entity.RolloutStep, error)); ok {
+		return rf(slug)
+	}
+	if rf, ok := ret.Get(0).(func(string) []entity.RolloutStep); ok {
+		r0 = rf(slug)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.RolloutStep)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(slug)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Segment provides a mock function with given fields: slug
+func (_m *SegmentRepo) Segment(slug string) (entity.SegmentInfo, error) {
+	ret := _m.Called(slug)
+
+	var r0 entity.SegmentInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (entity.SegmentInfo, error)); ok {
+		return rf(slug)
+	}
+	if rf, ok := ret.Get(0).(func(string) entity.SegmentInfo); ok {
+		r0 = rf(slug)
+	} else {
+		r0 = ret.Get(0).(entity.SegmentInfo)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(slug)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SegmentChanges provides a mock function with given fields: slug
+func (_m *SegmentRepo) SegmentChanges(slug string) ([]entity.SegmentChange, error) {
+	ret := _m.Called(slug)
+
+	var r0 []entity.SegmentChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]entity.SegmentChange, error)); ok {
+		return rf(slug)
+	}
+	if rf, ok := ret.Get(0).(func(string) []entity.SegmentChange); ok {
+		r0 = rf(slug)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.SegmentChange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(slug)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Segments provides a mock function with given fields: filter
+func (_m *SegmentRepo) Segments(filter entity.SegmentFilter) ([]entity.SegmentInfo, int, error) {
+	ret := _m.Called(filter)
+
+	var r0 []entity.SegmentInfo
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func(entity.SegmentFilter) ([]entity.SegmentInfo, int, error)); ok {
+		return rf(filter)
+	}
+	if rf, ok := ret.Get(0).(func(entity.SegmentFilter) []entity.SegmentInfo); ok {
+		r0 = rf(filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.SegmentInfo)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.SegmentFilter) int); ok {
+		r1 = rf(filter)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	if rf, ok := ret.Get(2).(func(entity.SegmentFilter) error); ok {
+		r2 = rf(filter)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// SetHoldout provides a mock function with given fields: h
+func (_m *SegmentRepo) SetHoldout(h entity.Holdout) error {
+	ret := _m.Called(h)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.Holdout) error); ok {
+		r0 = rf(h)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetRollout provides a mock function with given fields: slug, percent
+func (_m *SegmentRepo) SetRollout(slug string, percent int) (entity.RolloutChange, error) {
+	ret := _m.Called(slug, percent)
+
+	var r0 entity.RolloutChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int) (entity.RolloutChange, error)); ok {
+		return rf(slug, percent)
+	}
+	if rf, ok := ret.Get(0).(func(string, int) entity.RolloutChange); ok {
+		r0 = rf(slug, percent)
+	} else {
+		r0 = ret.Get(0).(entity.RolloutChange)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(slug, percent)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetRolloutSchedule provides a mock function with given fields: slug, steps
+func (_m *SegmentRepo) SetRolloutSchedule(slug string, steps []entity.RolloutStep) error {
+	ret := _m.Called(slug, steps)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []entity.RolloutStep) error); ok {
+		r0 = rf(slug, steps)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateSegment provides a mock function with given fields: slug, upd
+func (_m *SegmentRepo) UpdateSegment(slug string, upd entity.SegmentUpdate) (entity.SegmentInfo, error) {
+	ret := _m.Called(slug, upd)
+
+	var r0 entity.SegmentInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, entity.SegmentUpdate) (entity.SegmentInfo, error)); ok {
+		return rf(slug, upd)
+	}
+	if rf, ok := ret.Get(0).(func(string, entity.SegmentUpdate) entity.SegmentInfo); ok {
+		r0 = rf(slug, upd)
+	} else {
+		r0 = ret.Get(0).(entity.SegmentInfo)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, entity.SegmentUpdate) error); ok {
+		r1 = rf(slug, upd)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewSegmentRepo creates a new instance of SegmentRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewSegmentRepo(t interface {
@@ -5,6 +5,8 @@ package mocks
 import (
 	entity "experiment.io/internal/entity"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // UserRepo is an autogenerated mock type for the UserRepo type
@@ -26,6 +28,56 @@ func (_m *UserRepo) AddUserSegments(userID int, added []entity.SlugWithExpiredDa
 	return r0
 }
 
+// Holdout provides a mock function with given fields:
+func (_m *UserRepo) Holdout() (entity.Holdout, error) {
+	ret := _m.Called()
+
+	var r0 entity.Holdout
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (entity.Holdout, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() entity.Holdout); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(entity.Holdout)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LastUserOperations provides a mock function with given fields: userID, at
+func (_m *UserRepo) LastUserOperations(userID int, at time.Time) ([]entity.UserSegmentsHistory, error) {
+	ret := _m.Called(userID, at)
+
+	var r0 []entity.UserSegmentsHistory
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int, time.Time) ([]entity.UserSegmentsHistory, error)); ok {
+		return rf(userID, at)
+	}
+	if rf, ok := ret.Get(0).(func(int, time.Time) []entity.UserSegmentsHistory); ok {
+		r0 = rf(userID, at)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.UserSegmentsHistory)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int, time.Time) error); ok {
+		r1 = rf(userID, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RemoveUserSegments provides a mock function with given fields: userID, removed
 func (_m *UserRepo) RemoveUserSegments(userID int, removed []string) error {
 	ret := _m.Called(userID, removed)
@@ -40,6 +92,112 @@ func (_m *UserRepo) RemoveUserSegments(userID int, removed []string) error {
 	return r0
 }
 
+// SegmentRules provides a mock function with given fields:
+func (_m *UserRepo) SegmentRules() ([]entity.Segment, error) {
+	ret := _m.Called()
+
+	var r0 []entity.Segment
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]entity.Segment, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []entity.Segment); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Segment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetUserAttributes provides a mock function with given fields: userID, attributes
+func (_m *UserRepo) SetUserAttributes(userID int, attributes map[string]any) error {
+	ret := _m.Called(userID, attributes)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int, map[string]any) error); ok {
+		r0 = rf(userID, attributes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateUserSegments provides a mock function with given fields: userID, updated
+func (_m *UserRepo) UpdateUserSegments(userID int, updated []entity.SlugWithExpiredDate) error {
+	ret := _m.Called(userID, updated)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int, []entity.SlugWithExpiredDate) error); ok {
+		r0 = rf(userID, updated)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserAttributes provides a mock function with given fields: userID
+func (_m *UserRepo) UserAttributes(userID int) (map[string]any, error) {
+	ret := _m.Called(userID)
+
+	var r0 map[string]any
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int) (map[string]any, error)); ok {
+		return rf(userID)
+	}
+	if rf, ok := ret.Get(0).(func(int) map[string]any); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]any)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserHistory provides a mock function with given fields: userID, afterID, limit
+func (_m *UserRepo) UserHistory(userID int, afterID int, limit int) ([]entity.UserSegmentsHistory, error) {
+	ret := _m.Called(userID, afterID, limit)
+
+	var r0 []entity.UserSegmentsHistory
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int, int, int) ([]entity.UserSegmentsHistory, error)); ok {
+		return rf(userID, afterID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(int, int, int) []entity.UserSegmentsHistory); ok {
+		r0 = rf(userID, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.UserSegmentsHistory)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int, int, int) error); ok {
+		r1 = rf(userID, afterID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UserSegments provides a mock function with given fields: userID
 func (_m *UserRepo) UserSegments(userID int) ([]entity.SlugWithExpiredDate, error) {
 	ret := _m.Called(userID)
@@ -66,25 +224,25 @@ func (_m *UserRepo) UserSegments(userID int) ([]entity.SlugWithExpiredDate, erro
 	return r0, r1
 }
 
-// UsersHistoryInByDate provides a mock function with given fields: year, month
-func (_m *UserRepo) UsersHistoryInByDate(year int, month int) ([]entity.UserSegmentsHistory, error) {
-	ret := _m.Called(year, month)
+// UsersHistory provides a mock function with given fields: filter
+func (_m *UserRepo) UsersHistory(filter entity.HistoryFilter) ([]entity.UserSegmentsHistory, error) {
+	ret := _m.Called(filter)
 
 	var r0 []entity.UserSegmentsHistory
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int, int) ([]entity.UserSegmentsHistory, error)); ok {
-		return rf(year, month)
+	if rf, ok := ret.Get(0).(func(entity.HistoryFilter) ([]entity.UserSegmentsHistory, error)); ok {
+		return rf(filter)
 	}
-	if rf, ok := ret.Get(0).(func(int, int) []entity.UserSegmentsHistory); ok {
-		r0 = rf(year, month)
+	if rf, ok := ret.Get(0).(func(entity.HistoryFilter) []entity.UserSegmentsHistory); ok {
+		r0 = rf(filter)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]entity.UserSegmentsHistory)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int, int) error); ok {
-		r1 = rf(year, month)
+	if rf, ok := ret.Get(1).(func(entity.HistoryFilter) error); ok {
+		r1 = rf(filter)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -92,23 +250,23 @@ func (_m *UserRepo) UsersHistoryInByDate(year int, month int) ([]entity.UserSegm
 	return r0, r1
 }
 
-// WriteHistoryToCSV provides a mock function with given fields: history, year, month
-func (_m *UserRepo) WriteHistoryToCSV(history []entity.UserSegmentsHistory, year int, month int) (string, error) {
-	ret := _m.Called(history, year, month)
+// WriteHistoryToCSV provides a mock function with given fields: history, name
+func (_m *UserRepo) WriteHistoryToCSV(history []entity.UserSegmentsHistory, name string) (string, error) {
+	ret := _m.Called(history, name)
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func([]entity.UserSegmentsHistory, int, int) (string, error)); ok {
-		return rf(history, year, month)
+	if rf, ok := ret.Get(0).(func([]entity.UserSegmentsHistory, string) (string, error)); ok {
+		return rf(history, name)
 	}
-	if rf, ok := ret.Get(0).(func([]entity.UserSegmentsHistory, int, int) string); ok {
-		r0 = rf(history, year, month)
+	if rf, ok := ret.Get(0).(func([]entity.UserSegmentsHistory, string) string); ok {
+		r0 = rf(history, name)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func([]entity.UserSegmentsHistory, int, int) error); ok {
-		r1 = rf(history, year, month)
+	if rf, ok := ret.Get(1).(func([]entity.UserSegmentsHistory, string) error); ok {
+		r1 = rf(history, name)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -6,6 +6,8 @@ import (
 	entity "experiment.io/internal/entity"
 
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // UserUsecase is an autogenerated mock type for the UserUsecase type
@@ -41,6 +43,93 @@ func (_m *UserUsecase) RemoveUserSegments(userID int, removed []string) error {
 	return r0
 }
 
+// SetUserAttributes provides a mock function with given fields: userID, attributes
+func (_m *UserUsecase) SetUserAttributes(userID int, attributes map[string]any) error {
+	ret := _m.Called(userID, attributes)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int, map[string]any) error); ok {
+		r0 = rf(userID, attributes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateUserSegments provides a mock function with given fields: userID, updated
+func (_m *UserUsecase) UpdateUserSegments(userID int, updated []entity.SlugWithExpiredDate) error {
+	ret := _m.Called(userID, updated)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int, []entity.SlugWithExpiredDate) error); ok {
+		r0 = rf(userID, updated)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserAttributes provides a mock function with given fields: userID
+func (_m *UserUsecase) UserAttributes(userID int) (map[string]any, error) {
+	ret := _m.Called(userID)
+
+	var r0 map[string]any
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int) (map[string]any, error)); ok {
+		return rf(userID)
+	}
+	if rf, ok := ret.Get(0).(func(int) map[string]any); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]any)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserHistory provides a mock function with given fields: userID, afterID, limit
+func (_m *UserUsecase) UserHistory(userID int, afterID int, limit int) ([]entity.UserSegmentsHistory, int, error) {
+	ret := _m.Called(userID, afterID, limit)
+
+	var r0 []entity.UserSegmentsHistory
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func(int, int, int) ([]entity.UserSegmentsHistory, int, error)); ok {
+		return rf(userID, afterID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(int, int, int) []entity.UserSegmentsHistory); ok {
+		r0 = rf(userID, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.UserSegmentsHistory)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int, int, int) int); ok {
+		r1 = rf(userID, afterID, limit)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	if rf, ok := ret.Get(2).(func(int, int, int) error); ok {
+		r2 = rf(userID, afterID, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // UserSegments provides a mock function with given fields: userID
 func (_m *UserUsecase) UserSegments(userID int) ([]entity.SlugWithExpiredDate, error) {
 	ret := _m.Called(userID)
@@ -67,23 +156,49 @@ func (_m *UserUsecase) UserSegments(userID int) ([]entity.SlugWithExpiredDate, e
 	return r0, r1
 }
 
-// UsersHistoryInCSVByDate provides a mock function with given fields: year, month
-func (_m *UserUsecase) UsersHistoryInCSVByDate(year int, month int) (string, error) {
-	ret := _m.Called(year, month)
+// UserSegmentsAt provides a mock function with given fields: userID, at
+func (_m *UserUsecase) UserSegmentsAt(userID int, at time.Time) ([]entity.SlugWithExpiredDate, error) {
+	ret := _m.Called(userID, at)
+
+	var r0 []entity.SlugWithExpiredDate
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int, time.Time) ([]entity.SlugWithExpiredDate, error)); ok {
+		return rf(userID, at)
+	}
+	if rf, ok := ret.Get(0).(func(int, time.Time) []entity.SlugWithExpiredDate); ok {
+		r0 = rf(userID, at)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.SlugWithExpiredDate)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int, time.Time) error); ok {
+		r1 = rf(userID, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UsersHistoryInCSV provides a mock function with given fields: filter
+func (_m *UserUsecase) UsersHistoryInCSV(filter entity.HistoryFilter) (string, error) {
+	ret := _m.Called(filter)
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int, int) (string, error)); ok {
-		return rf(year, month)
+	if rf, ok := ret.Get(0).(func(entity.HistoryFilter) (string, error)); ok {
+		return rf(filter)
 	}
-	if rf, ok := ret.Get(0).(func(int, int) string); ok {
-		r0 = rf(year, month)
+	if rf, ok := ret.Get(0).(func(entity.HistoryFilter) string); ok {
+		r0 = rf(filter)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(int, int) error); ok {
-		r1 = rf(year, month)
+	if rf, ok := ret.Get(1).(func(entity.HistoryFilter) error); ok {
+		r1 = rf(filter)
 	} else {
 		r1 = ret.Error(1)
 	}
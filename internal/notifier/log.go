@@ -0,0 +1,24 @@
+package notifier
+
+import (
+	"fmt"
+	"time"
+
+	"experiment.io/internal/entity"
+	"experiment.io/pkg/logger"
+)
+
+// Writes notifications to the service log
+type Log struct {
+	l *logger.Logger
+}
+
+func NewLog(l *logger.Logger) *Log {
+	return &Log{l}
+}
+
+func (n *Log) Notify(notification entity.ExpiryNotification) error {
+	n.l.Info(fmt.Sprintf("membership of user %d in segment %s expires at %s",
+		notification.UserID, notification.SegmentSlug, notification.ExpiredDate.Format(time.RFC3339)))
+	return nil
+}
@@ -0,0 +1,35 @@
+package notifier
+
+import (
+	"sync"
+
+	"experiment.io/internal/entity"
+)
+
+// Keeps notifications in memory, used in tests
+type Memory struct {
+	mu            sync.Mutex
+	notifications []entity.ExpiryNotification
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Notify(n entity.ExpiryNotification) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.notifications = append(m.notifications, n)
+	return nil
+}
+
+// Returns a copy of the received notifications in the order of delivery
+func (m *Memory) Notifications() []entity.ExpiryNotification {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notifications := make([]entity.ExpiryNotification, len(m.notifications))
+	copy(notifications, m.notifications)
+	return notifications
+}
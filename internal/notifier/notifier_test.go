@@ -0,0 +1,86 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"experiment.io/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookNotify(t *testing.T) {
+	notification := entity.ExpiryNotification{
+		SegmentSlug: "AVITO_DISCOUNT_30",
+		UserID:      1,
+		ExpiredDate: time.Date(2023, 9, 28, 12, 0, 0, 0, time.UTC),
+	}
+
+	testCase := []struct {
+		name        string
+		status      int
+		expectedErr error
+	}{
+		{
+			name:        "Delivered",
+			status:      http.StatusOK,
+			expectedErr: nil,
+		},
+		{
+			name:        "Accepted",
+			status:      http.StatusAccepted,
+			expectedErr: nil,
+		},
+		{
+			name:        "Rejected",
+			status:      http.StatusInternalServerError,
+			expectedErr: entity.ErrNotificationRejected,
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			var payload webhookPayload
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+				require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+				w.WriteHeader(tc.status)
+			}))
+			defer srv.Close()
+
+			err := NewWebhook(srv.URL, time.Second).Notify(notification)
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, "membership_expiring", payload.Event)
+			require.Equal(t, notification.SegmentSlug, payload.SegmentSlug)
+			require.Equal(t, notification.UserID, payload.UserID)
+			require.True(t, notification.ExpiredDate.Equal(payload.ExpiresAt))
+		})
+	}
+}
+
+func TestWebhookNotifyUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+
+	err := NewWebhook(srv.URL, time.Second).Notify(entity.ExpiryNotification{SegmentSlug: "AVITO_DISCOUNT_30", UserID: 1})
+	require.Error(t, err)
+}
+
+func TestMemoryNotify(t *testing.T) {
+	m := NewMemory()
+	require.Empty(t, m.Notifications())
+
+	first := entity.ExpiryNotification{SegmentSlug: "AVITO_DISCOUNT_30", UserID: 1}
+	second := entity.ExpiryNotification{SegmentSlug: "AVITO_DISCOUNT_50", UserID: 2}
+	require.NoError(t, m.Notify(first))
+	require.NoError(t, m.Notify(second))
+
+	notifications := m.Notifications()
+	require.Equal(t, []entity.ExpiryNotification{first, second}, notifications)
+
+	notifications[0].UserID = 3
+	require.Equal(t, first, m.Notifications()[0])
+}
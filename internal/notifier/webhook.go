@@ -0,0 +1,54 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"experiment.io/internal/entity"
+)
+
+// Posts every notification as JSON to the URL, any non-2xx response is an error
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhook(url string, timeout time.Duration) *Webhook {
+	return &Webhook{url, &http.Client{Timeout: timeout}}
+}
+
+type webhookPayload struct {
+	Event       string    `json:"event"`
+	SegmentSlug string    `json:"segment_slug"`
+	UserID      int       `json:"user_id"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func (w *Webhook) Notify(n entity.ExpiryNotification) error {
+	op := "notifier.webhook.Notify"
+
+	body, err := json.Marshal(webhookPayload{
+		Event:       "membership_expiring",
+		SegmentSlug: n.SegmentSlug,
+		UserID:      n.UserID,
+		ExpiresAt:   n.ExpiredDate,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: status %d: %w", op, resp.StatusCode, entity.ErrNotificationRejected)
+	}
+
+	return nil
+}
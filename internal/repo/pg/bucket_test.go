@@ -0,0 +1,27 @@
+package pg
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"experiment.io/pkg/bucket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserBucketMatchesGo(t *testing.T) {
+	db := testPostgres(t)
+
+	ids := []int{0, 1, 2, 42, 1000, 123456789, math.MaxInt32}
+	for id := 3; id <= 100; id++ {
+		ids = append(ids, id)
+	}
+	for _, salt := range []string{"", "SEGMENT", "AVITO_DISCOUNT_30", "checkout:v2", "скидка"} {
+		for _, id := range ids {
+			var b int
+			err := db.QueryRow(context.TODO(), `SELECT user_bucket($1, $2)`, salt, id).Scan(&b)
+			require.NoError(t, err)
+			require.Equal(t, bucket.Of(salt, id), b, "salt %q, user %d", salt, id)
+		}
+	}
+}
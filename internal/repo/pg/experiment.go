@@ -0,0 +1,142 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"experiment.io/internal/entity"
+	"experiment.io/pkg/storage/pg"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type ExperimentRepository struct {
+	db *pg.Postgres
+}
+
+func NewExperimentRepository(db *pg.Postgres) *ExperimentRepository {
+	return &ExperimentRepository{db}
+}
+
+// Creates an experiment with a segment per variant, splits all users between the variants
+// and returns the user IDs assigned to every variant.
+// Users already in another segment of the experiment layer are left out
+func (r *ExperimentRepository) NewExperiment(exp entity.Experiment) (map[string][]int, error) {
+	op := "repo.pg.experiment.New"
+
+	tx, err := r.db.Begin(context.TODO())
+	defer tx.Rollback(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := `
+	INSERT INTO experiments
+	(slug, salt, layer)
+	VALUES($1, $2, NULLIF($3, ''))
+	`
+	if _, err := tx.Exec(context.TODO(), query, exp.Slug, exp.Salt, exp.Layer); err != nil {
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok && pgErr.Code == DuplicatePKErrCode {
+			return nil, fmt.Errorf("%s: %w", op, entity.ErrExperimentAlreadyExist)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query = `
+	INSERT INTO segments
+	(slug, salt, experiment_slug, variant, variant_weight, layer)
+	VALUES($1, $2, $3, $4, $5, NULLIF($6, ''))
+	`
+	variants := make(map[string]string, len(exp.Variants))
+	for _, v := range exp.Variants {
+		if _, err := tx.Exec(context.TODO(), query, v.Segment, exp.Salt, exp.Slug, v.Name, v.Weight, exp.Layer); err != nil {
+			var pgErr *pgconn.PgError
+			if ok := errors.As(err, &pgErr); ok && pgErr.Code == DuplicatePKErrCode {
+				return nil, fmt.Errorf("%s: %w", op, entity.ErrSegmentAlreadyExist)
+			}
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		variants[v.Segment] = v.Name
+	}
+
+	query = `
+	INSERT INTO segments_to_users
+	(segment_slug, user_id, expiration_date)
+	SELECT v.slug, v.id, 'INFINITY'
+	FROM (SELECT experiment_variant($1, id) AS slug, id FROM users) v
+	WHERE assignment_violation(v.slug, v.id) IS NULL
+	ORDER BY v.id
+	RETURNING segment_slug, user_id
+	`
+	rows, err := tx.Query(context.TODO(), query, exp.Slug)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	assigned := make(map[string][]int, len(exp.Variants))
+	for rows.Next() {
+		var slug string
+		var id int
+		if err := rows.Scan(&slug, &id); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		assigned[variants[slug]] = append(assigned[variants[slug]], id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	rows.Close()
+
+	err = tx.Commit(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return assigned, nil
+}
+
+// Returns the experiment with its variants and the number of their active members
+func (r *ExperimentRepository) Experiment(slug string) (entity.Experiment, error) {
+	op := "repo.pg.experiment.Experiment"
+
+	query := `
+	SELECT e.salt, COALESCE(e.layer, ''), s.variant, s.variant_weight, s.slug,
+		(SELECT COUNT(*) FROM segments_to_users su WHERE su.segment_slug = s.slug AND su.expiration_date > NOW())
+	FROM experiments e
+	JOIN segments s ON s.experiment_slug = e.slug
+	WHERE e.slug = $1
+	ORDER BY s.slug
+	`
+	rows, err := r.db.Query(context.TODO(), query, slug)
+	if err != nil {
+		return entity.Experiment{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	exp := entity.Experiment{Slug: slug}
+	for rows.Next() {
+		var v entity.Variant
+		if err := rows.Scan(
+			&exp.Salt,
+			&exp.Layer,
+			&v.Name,
+			&v.Weight,
+			&v.Segment,
+			&v.Members,
+		); err != nil {
+			return entity.Experiment{}, fmt.Errorf("%s: %w", op, err)
+		}
+		exp.Variants = append(exp.Variants, v)
+	}
+	if err := rows.Err(); err != nil {
+		return entity.Experiment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(exp.Variants) == 0 {
+		return entity.Experiment{}, fmt.Errorf("%s: %w", op, entity.ErrExperimentNotFound)
+	}
+
+	return exp, nil
+}
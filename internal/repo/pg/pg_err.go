@@ -1,8 +1,21 @@
 package pg
 
 const (
-	NonExistentFKErrCode = "23503"
-	DuplicatePKErrCode   = "23505"
-	InvalidSegmentFK     = "segments_to_users_segment_slug_fkey"
-	InvalidUserFK        = "segments_to_users_user_id_fkey"
+	NonExistentFKErrCode  = "23503"
+	DuplicatePKErrCode    = "23505"
+	CheckViolationCode    = "23514"
+	InvalidSegmentFK      = "segments_to_users_segment_slug_fkey"
+	InvalidUserFK         = "segments_to_users_user_id_fkey"
+	InvalidPrerequisiteFK = "segment_prerequisites_prerequisite_slug_fkey"
+	SegmentWindowCheck    = "segments_window_check"
+
+	// raised by the segments_to_users guard trigger
+	VariantConflictErrCode = "EX001"
+	LayerConflictErrCode   = "EX002"
+	SegmentArchivedErrCode = "EX003"
+	HoldoutErrCode         = "EX004"
+	PrerequisiteErrCode    = "EX005"
+	SegmentFullErrCode     = "EX006"
+	// raised by the segments_to_users removal policy trigger
+	PrerequisiteRequiredErrCode = "EX007"
 )
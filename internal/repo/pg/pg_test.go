@@ -0,0 +1,59 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"experiment.io/pkg/storage/pg"
+	"github.com/stretchr/testify/require"
+)
+
+// Repository tests run against a migrated database given by TEST_DB_URL and are skipped without it
+func testPostgres(t *testing.T) *pg.Postgres {
+	t.Helper()
+
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+	db, err := pg.New(url)
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+
+	return db
+}
+
+// Creates a user removed with his memberships after the test
+func testUser(t *testing.T, db *pg.Postgres) int {
+	t.Helper()
+
+	var id int
+	err := db.QueryRow(context.TODO(), `
+	INSERT INTO users (name, encrypted_pwd) VALUES ($1, '') RETURNING id
+	`, fmt.Sprintf("test_%d", time.Now().UnixNano())).Scan(&id)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Exec(context.TODO(), `DELETE FROM users WHERE id = $1`, id)
+	})
+
+	return id
+}
+
+// Creates a segment with a unique slug that ignores the holdout and removes it after the test
+func testSegment(t *testing.T, db *pg.Postgres, prefix string) string {
+	t.Helper()
+
+	slug := fmt.Sprintf("%s_%d", prefix, time.Now().UnixNano())
+	_, err := db.Exec(context.TODO(), `
+	INSERT INTO segments (slug, allow_holdout) VALUES ($1, TRUE)
+	`, slug)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Exec(context.TODO(), `DELETE FROM segments WHERE slug = $1`, slug)
+	})
+
+	return slug
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"experiment.io/internal/entity"
 	"experiment.io/pkg/storage/pg"
+	pgx "github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
@@ -21,17 +24,38 @@ func NewSegmentRepository(db *pg.Postgres) *SegmentRepository {
 func (r *SegmentRepository) NewSegment(seg entity.Segment) error {
 	op := "repo.pg.segment.New"
 
+	tx, err := r.db.Begin(context.TODO())
+	defer tx.Rollback(context.TODO())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	query := `
 	INSERT INTO segments
-	(slug) 
-	VALUES($1)
+	(slug, layer, targeting_rule, description, owner, tags, starts_at, ends_at, allow_holdout, removal_policy,
+	max_members) 
+	VALUES($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10, $11)
 	`
 
-	if _, err := r.db.Exec(context.TODO(), query, seg.Slug); err != nil {
+	if _, err := tx.Exec(context.TODO(), query, seg.Slug, seg.Layer, seg.Rule,
+		seg.Description, seg.Owner, nonNilTags(seg.Tags), seg.StartsAt, seg.EndsAt, seg.AllowHoldout,
+		string(seg.RemovalPolicy), seg.MaxMembers); err != nil {
 		var pgErr *pgconn.PgError
 		if ok := errors.As(err, &pgErr); ok && pgErr.Code == DuplicatePKErrCode {
 			return fmt.Errorf("%s: %w", op, entity.ErrSegmentAlreadyExist)
 		}
+		if ok := errors.As(err, &pgErr); ok && pgErr.ConstraintName == SegmentWindowCheck {
+			return fmt.Errorf("%s: %w", op, entity.ErrInvalidSegmentWindow)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := replacePrerequisites(tx, seg.Slug, seg.Prerequisites); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.Commit(context.TODO())
+	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -42,40 +66,475 @@ func (r *SegmentRepository) NewSegment(seg entity.Segment) error {
 func (r *SegmentRepository) NewSegmentWithAutoAssign(seg entity.Segment, percentAssigned int) ([]int, error) {
 	op := "repo.pg.segment.NewWithAutoAssign"
 
+	tx, err := r.db.Begin(context.TODO())
+	defer tx.Rollback(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
 	query := `
-	SELECT * FROM create_segment_and_add_users($1, $2);
+	INSERT INTO segments
+	(slug, salt, rollout_percent, deterministic, layer, description, owner, tags, allow_holdout, max_members,
+	rollout_expires_at)
+	VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, $11)
 	`
-	rows, err := r.db.Query(context.TODO(), query, seg.Slug, percentAssigned)
-	if err != nil {
+	deterministic := seg.AssignMode == entity.AssignModeHash
+	if _, err := tx.Exec(context.TODO(), query, seg.Slug, seg.Salt, percentAssigned, deterministic, seg.Layer,
+		seg.Description, seg.Owner, nonNilTags(seg.Tags), seg.AllowHoldout, seg.MaxMembers,
+		seg.RolloutExpiresAt); err != nil {
 		var pgErr *pgconn.PgError
 		if ok := errors.As(err, &pgErr); ok && pgErr.Code == DuplicatePKErrCode {
 			return nil, fmt.Errorf("%s: %w", op, entity.ErrSegmentAlreadyExist)
 		}
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+
+	query = `
+	SELECT user_id FROM set_segment_rollout($1, $2);
+	`
+	rows, err := tx.Query(context.TODO(), query, seg.Slug, percentAssigned)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	rows.Close()
+
+	err = tx.Commit(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+// Raises or lowers the rollout percent of the segment and returns the added and removed users.
+// Only percentage segments can be changed, manual and variant segments are refused
+func (r *SegmentRepository) SetRollout(slug string, percent int) (entity.RolloutChange, error) {
+	op := "repo.pg.segment.SetRollout"
+
+	tx, err := r.db.Begin(context.TODO())
+	defer tx.Rollback(context.TODO())
+	if err != nil {
+		return entity.RolloutChange{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := `
+	SELECT archived_at IS NOT NULL, rollout_percent IS NOT NULL AND experiment_slug IS NULL FROM segments
+	WHERE slug = $1
+	FOR UPDATE
+	`
+	var archived, rollout bool
+	if err := tx.QueryRow(context.TODO(), query, slug).Scan(&archived, &rollout); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.RolloutChange{}, fmt.Errorf("%s: %w", op, entity.ErrSegmentNotFound)
+		}
+		return entity.RolloutChange{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if archived {
+		return entity.RolloutChange{}, fmt.Errorf("%s: %w", op, entity.ErrSegmentArchived)
+	}
+	if !rollout {
+		return entity.RolloutChange{}, fmt.Errorf("%s: %w", op, entity.ErrNotRolloutSegment)
+	}
+
+	query = `
+	SELECT * FROM set_segment_rollout($1, $2);
+	`
+	rows, err := tx.Query(context.TODO(), query, slug, percent)
+	if err != nil {
+		return entity.RolloutChange{}, checkUserToSegmentError(op, err)
+	}
+	defer rows.Close()
+
+	change := entity.RolloutChange{Added: []int{}, Removed: []int{}}
+	for rows.Next() {
+		var id int
+		var added bool
+		if err := rows.Scan(&id, &added); err != nil {
+			return entity.RolloutChange{}, fmt.Errorf("%s: %w", op, err)
+		}
+		if added {
+			change.Added = append(change.Added, id)
+		} else {
+			change.Removed = append(change.Removed, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return entity.RolloutChange{}, checkUserToSegmentError(op, err)
+	}
+	rows.Close()
+
+	err = tx.Commit(context.TODO())
+	if err != nil {
+		return entity.RolloutChange{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return change, nil
+}
+
+// Adds the percent of users that are not in the segment yet and returns them.
+// Memberships never expire when expiresAt is nil
+func (r *SegmentRepository) AutoAssignSegment(slug string, percent int, expiresAt *time.Time) ([]int, error) {
+	op := "repo.pg.segment.AutoAssign"
+
+	tx, err := r.db.Begin(context.TODO())
+	defer tx.Rollback(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := `
+	SELECT archived_at IS NOT NULL FROM segments
+	WHERE slug = $1
+	FOR UPDATE
+	`
+	var archived bool
+	if err := tx.QueryRow(context.TODO(), query, slug).Scan(&archived); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, entity.ErrSegmentNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if archived {
+		return nil, fmt.Errorf("%s: %w", op, entity.ErrSegmentArchived)
+	}
+
+	query = `
+	SELECT user_id FROM add_random_users($1, $2, $3);
+	`
+	rows, err := tx.Query(context.TODO(), query, slug, percent, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 	defer rows.Close()
 
 	ids := []int{}
 	for rows.Next() {
-		var isCreated bool
 		var id int
-		if err := rows.Scan(&id, &isCreated); err != nil {
+		if err := rows.Scan(&id); err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
-		if !isCreated {
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	rows.Close()
+
+	err = tx.Commit(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+// Replaces the pending steps of the segment rollout schedule, applied steps are kept.
+// Only percentage segments can have a schedule, the pending steps of other segments can only be removed
+func (r *SegmentRepository) SetRolloutSchedule(slug string, steps []entity.RolloutStep) error {
+	op := "repo.pg.segment.SetRolloutSchedule"
+
+	tx, err := r.db.Begin(context.TODO())
+	defer tx.Rollback(context.TODO())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := `
+	SELECT rollout_percent IS NOT NULL AND experiment_slug IS NULL FROM segments
+	WHERE slug = $1
+	FOR UPDATE
+	`
+	var rollout bool
+	if err := tx.QueryRow(context.TODO(), query, slug).Scan(&rollout); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, entity.ErrSegmentNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !rollout && len(steps) > 0 {
+		return fmt.Errorf("%s: %w", op, entity.ErrNotRolloutSegment)
+	}
+
+	query = `
+	DELETE FROM rollout_steps
+	WHERE segment_slug = $1 AND applied_at IS NULL
+	`
+	if _, err := tx.Exec(context.TODO(), query, slug); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query = `
+	INSERT INTO rollout_steps
+	(segment_slug, percent, apply_at)
+	VALUES ($1, $2, $3)
+	`
+	for _, step := range steps {
+		if _, err := tx.Exec(context.TODO(), query, slug, step.Percent, step.ApplyAt); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	err = tx.Commit(context.TODO())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Returns applied and pending steps of the segment rollout schedule ordered by time
+func (r *SegmentRepository) RolloutSchedule(slug string) ([]entity.RolloutStep, error) {
+	op := "repo.pg.segment.RolloutSchedule"
+
+	query := `
+	SELECT step_id, segment_slug, percent::INTEGER, apply_at, applied_at, attempts, COALESCE(last_error, '')
+	FROM rollout_steps
+	WHERE segment_slug = $1
+	ORDER BY apply_at, step_id
+	`
+	rows, err := r.db.Query(context.TODO(), query, slug)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	steps := []entity.RolloutStep{}
+	for rows.Next() {
+		var step entity.RolloutStep
+		if err := rows.Scan(
+			&step.ID,
+			&step.SegmentSlug,
+			&step.Percent,
+			&step.ApplyAt,
+			&step.AppliedAt,
+			&step.Attempts,
+			&step.LastError,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		steps = append(steps, step)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return steps, nil
+}
+
+// Applies the earliest due step of active segments and marks it applied in the same transaction.
+// Steps locked by another scheduler are skipped. Returns false if there is no due step.
+// A step that can not be applied is returned with the recorded failure and without AppliedAt,
+// steps with the skipped IDs are not picked, so one failing step does not block the others.
+// Steps of a segment are applied in order, later steps wait for the failed one
+func (r *SegmentRepository) ApplyNextRolloutStep(skipped []int) (entity.RolloutStep, bool, error) {
+	op := "repo.pg.segment.ApplyNextRolloutStep"
+
+	tx, err := r.db.Begin(context.TODO())
+	defer tx.Rollback(context.TODO())
+	if err != nil {
+		return entity.RolloutStep{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := `
+	SELECT rs.step_id, rs.segment_slug, rs.percent::INTEGER, rs.apply_at, rs.attempts,
+	s.rollout_percent IS NOT NULL AND s.experiment_slug IS NULL
+	FROM rollout_steps rs
+	JOIN segments s ON s.slug = rs.segment_slug
+	WHERE rs.applied_at IS NULL AND rs.apply_at <= NOW() AND s.archived_at IS NULL
+	AND rs.step_id <> ALL(COALESCE($1::INTEGER[], '{}'))
+	AND NOT EXISTS (
+		SELECT 1 FROM rollout_steps prev
+		WHERE prev.segment_slug = rs.segment_slug AND prev.applied_at IS NULL
+		AND (prev.apply_at, prev.step_id) < (rs.apply_at, rs.step_id)
+	)
+	ORDER BY rs.apply_at, rs.step_id
+	LIMIT 1
+	FOR UPDATE OF rs SKIP LOCKED
+	`
+	var step entity.RolloutStep
+	var rollout bool
+	if err := tx.QueryRow(context.TODO(), query, skipped).Scan(
+		&step.ID,
+		&step.SegmentSlug,
+		&step.Percent,
+		&step.ApplyAt,
+		&step.Attempts,
+		&rollout,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.RolloutStep{}, false, nil
+		}
+		return entity.RolloutStep{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if applyErr := applyRolloutStep(tx, &step, rollout); applyErr != nil {
+		query = `
+		UPDATE rollout_steps SET
+		attempts = attempts + 1,
+		last_error = $2,
+		failed_at = NOW()
+		WHERE step_id = $1
+		`
+		step.Attempts++
+		step.LastError = applyErr.Error()
+		if _, err := tx.Exec(context.TODO(), query, step.ID, step.LastError); err != nil {
+			return entity.RolloutStep{}, false, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	err = tx.Commit(context.TODO())
+	if err != nil {
+		return entity.RolloutStep{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return step, true, nil
+}
+
+// Sets the rollout percent of the step and marks it applied. Changes are made under a savepoint,
+// so a failure is rolled back without aborting the transaction
+func applyRolloutStep(tx pgx.Tx, step *entity.RolloutStep, rollout bool) error {
+	if !rollout {
+		return entity.ErrNotRolloutSegment
+	}
+
+	sp, err := tx.Begin(context.TODO())
+	if err != nil {
+		return err
+	}
+	defer sp.Rollback(context.TODO())
+
+	query := `
+	SELECT COUNT(*) FROM set_segment_rollout($1, $2);
+	`
+	var changed int
+	if err := sp.QueryRow(context.TODO(), query, step.SegmentSlug, step.Percent).Scan(&changed); err != nil {
+		return err
+	}
+
+	query = `
+	UPDATE rollout_steps SET
+	applied_at = NOW()
+	WHERE step_id = $1
+	RETURNING applied_at
+	`
+	if err := sp.QueryRow(context.TODO(), query, step.ID).Scan(&step.AppliedAt); err != nil {
+		return err
+	}
+
+	return sp.Commit(context.TODO())
+}
+
+// Enrolls users into percentage segments until every segment matches its rollout rule
+// and returns the number of added memberships
+func (r *SegmentRepository) BackfillRollouts() (int, error) {
+	op := "repo.pg.segment.BackfillRollouts"
+
+	query := `
+	SELECT COUNT(*) FROM backfill_rollouts();
+	`
+	var added int
+	if err := r.db.QueryRow(context.TODO(), query).Scan(&added); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return added, nil
+}
+
+// Creates a segment from the users of the set expression over active memberships of other segments
+// and returns the user IDs assigned to it. Users the segment can not be assigned to are skipped
+func (r *SegmentRepository) NewSegmentFromExpression(seg entity.Segment, expr entity.SegmentExpression) ([]int, error) {
+	op := "repo.pg.segment.NewFromExpression"
+
+	tx, err := r.db.Begin(context.TODO())
+	defer tx.Rollback(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slugs := expressionSlugs(expr, map[string]bool{})
+	query := `
+	SELECT COUNT(*) FROM segments
+	WHERE slug = ANY($1::VARCHAR[])
+	`
+	var found int
+	if err := tx.QueryRow(context.TODO(), query, slugs).Scan(&found); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if found != len(slugs) {
+		return nil, fmt.Errorf("%s: %w", op, entity.ErrSegmentNotFound)
+	}
+
+	query = `
+	INSERT INTO segments
+	(slug, description, owner, tags, allow_holdout)
+	VALUES ($1, $2, $3, $4, $5)
+	`
+	if _, err := tx.Exec(context.TODO(), query, seg.Slug, seg.Description, seg.Owner, nonNilTags(seg.Tags),
+		seg.AllowHoldout); err != nil {
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok && pgErr.Code == DuplicatePKErrCode {
 			return nil, fmt.Errorf("%s: %w", op, entity.ErrSegmentAlreadyExist)
 		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	args := []any{seg.Slug}
+	query = fmt.Sprintf(`
+	INSERT INTO segments_to_users
+	(segment_slug, user_id, expiration_date)
+	SELECT $1, result.user_id, 'INFINITY'
+	FROM (%s) AS result
+	WHERE assignment_violation($1, result.user_id) IS NULL
+	ORDER BY result.user_id
+	RETURNING user_id
+	`, compileExpression(expr, &args))
+	rows, err := tx.Query(context.TODO(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
 		ids = append(ids, id)
 	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.Commit(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
 	return ids, nil
 }
 
-func (r *SegmentRepository) DeleteSegment(slug string) error {
-	op := "repo.pg.segment.Delete"
+// Archives the segment, its memberships and history are kept
+func (r *SegmentRepository) ArchiveSegment(slug string) error {
+	op := "repo.pg.segment.Archive"
 
 	query := `
-	DELETE FROM segments
+	UPDATE segments SET
+	archived_at = COALESCE(archived_at, NOW())
 	WHERE slug = $1
 	`
 	res, err := r.db.Exec(context.TODO(), query, slug)
@@ -88,3 +547,544 @@ func (r *SegmentRepository) DeleteSegment(slug string) error {
 
 	return nil
 }
+
+// Restores the archived segment, a segment that is not archived is not touched
+func (r *SegmentRepository) RestoreSegment(slug string) error {
+	op := "repo.pg.segment.Restore"
+
+	query := `
+	UPDATE segments SET
+	archived_at = NULL,
+	updated_at = NOW()
+	WHERE slug = $1 AND archived_at IS NOT NULL
+	`
+	res, err := r.db.Exec(context.TODO(), query, slug)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		query = `
+		SELECT EXISTS(SELECT 1 FROM segments WHERE slug = $1)
+		`
+		var exists bool
+		if err := r.db.QueryRow(context.TODO(), query, slug).Scan(&exists); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if !exists {
+			return fmt.Errorf("%s: %w", op, entity.ErrSegmentNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, entity.ErrSegmentNotArchived)
+	}
+
+	return nil
+}
+
+// Creates a copy of the segment with its metadata and prerequisites and returns the user IDs
+// whose memberships were copied. Layer and experiment are not copied, so the members can be copied
+func (r *SegmentRepository) CloneSegment(slug string, clone entity.SegmentClone) ([]int, error) {
+	op := "repo.pg.segment.Clone"
+
+	tx, err := r.db.Begin(context.TODO())
+	defer tx.Rollback(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := `
+	INSERT INTO segments
+	(slug, targeting_rule, description, owner, tags, starts_at, ends_at, allow_holdout, removal_policy, max_members)
+	SELECT $2, targeting_rule, description, owner, tags, starts_at, ends_at, allow_holdout, removal_policy, max_members
+	FROM segments
+	WHERE slug = $1
+	`
+	res, err := tx.Exec(context.TODO(), query, slug, clone.Slug)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok && pgErr.Code == DuplicatePKErrCode {
+			return nil, fmt.Errorf("%s: %w", op, entity.ErrSegmentAlreadyExist)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return nil, fmt.Errorf("%s: %w", op, entity.ErrSegmentNotFound)
+	}
+
+	query = `
+	INSERT INTO segment_prerequisites
+	(segment_slug, prerequisite_slug)
+	SELECT $2, prerequisite_slug FROM segment_prerequisites
+	WHERE segment_slug = $1
+	`
+	if _, err := tx.Exec(context.TODO(), query, slug, clone.Slug); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids := []int{}
+	if clone.CopyMembers {
+		// the audit trigger logs every copied membership as an addition
+		query = `
+		INSERT INTO segments_to_users
+		(segment_slug, user_id, expiration_date)
+		SELECT $2, user_id, CASE WHEN $3 THEN 'INFINITY' ELSE expiration_date END
+		FROM segments_to_users
+		WHERE segment_slug = $1 AND expiration_date > NOW()
+		ORDER BY user_id
+		RETURNING user_id
+		`
+		rows, err := tx.Query(context.TODO(), query, slug, clone.Slug, clone.ResetExpiration)
+		if err != nil {
+			return nil, checkUserToSegmentError(op, err)
+		}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, checkUserToSegmentError(op, err)
+		}
+	}
+
+	err = tx.Commit(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+// Deletes the archived segment with all its memberships
+func (r *SegmentRepository) PurgeSegment(slug string) error {
+	op := "repo.pg.segment.Purge"
+
+	tx, err := r.db.Begin(context.TODO())
+	defer tx.Rollback(context.TODO())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := `
+	SELECT archived_at IS NOT NULL FROM segments
+	WHERE slug = $1
+	FOR UPDATE
+	`
+	var archived bool
+	if err := tx.QueryRow(context.TODO(), query, slug).Scan(&archived); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, entity.ErrSegmentNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !archived {
+		return fmt.Errorf("%s: %w", op, entity.ErrSegmentNotArchived)
+	}
+
+	query = `
+	DELETE FROM segments
+	WHERE slug = $1
+	`
+	if _, err := tx.Exec(context.TODO(), query, slug); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.Commit(context.TODO())
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Edits segment metadata and returns the updated segment
+func (r *SegmentRepository) UpdateSegment(slug string, upd entity.SegmentUpdate) (entity.SegmentInfo, error) {
+	op := "repo.pg.segment.Update"
+
+	tx, err := r.db.Begin(context.TODO())
+	defer tx.Rollback(context.TODO())
+	if err != nil {
+		return entity.SegmentInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// the row is locked before the new values are computed, updated_at is only bumped when one of them changes
+	query := `
+	WITH upd AS (
+		SELECT slug,
+		COALESCE($2, description) AS description,
+		COALESCE($3, owner) AS owner,
+		COALESCE($4::TEXT[], tags) AS tags,
+		CASE WHEN $10 THEN NULL ELSE COALESCE($5, starts_at) END AS starts_at,
+		CASE WHEN $10 THEN NULL ELSE COALESCE($6, ends_at) END AS ends_at,
+		COALESCE($7, allow_holdout) AS allow_holdout,
+		COALESCE($8, removal_policy) AS removal_policy,
+		CASE WHEN $11 THEN NULL ELSE COALESCE($9, max_members) END AS max_members
+		FROM segments
+		WHERE slug = $1
+		FOR UPDATE
+	)
+	UPDATE segments s SET
+	description = upd.description,
+	owner = upd.owner,
+	tags = upd.tags,
+	starts_at = upd.starts_at,
+	ends_at = upd.ends_at,
+	allow_holdout = upd.allow_holdout,
+	removal_policy = upd.removal_policy,
+	max_members = upd.max_members,
+	updated_at = CASE
+		WHEN (s.description, s.owner, s.tags, s.starts_at, s.ends_at, s.allow_holdout, s.removal_policy, s.max_members)
+		IS DISTINCT FROM (upd.description, upd.owner, upd.tags, upd.starts_at, upd.ends_at, upd.allow_holdout,
+		upd.removal_policy, upd.max_members)
+		THEN NOW() ELSE s.updated_at END
+	FROM upd
+	WHERE s.slug = upd.slug
+	`
+	var removalPolicy *string
+	if upd.RemovalPolicy != nil {
+		policy := string(*upd.RemovalPolicy)
+		removalPolicy = &policy
+	}
+	res, err := tx.Exec(context.TODO(), query, slug, upd.Description, upd.Owner, upd.Tags, upd.StartsAt, upd.EndsAt,
+		upd.AllowHoldout, removalPolicy, upd.MaxMembers, upd.ClearWindow, upd.ClearMaxMembers)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if ok := errors.As(err, &pgErr); ok && pgErr.ConstraintName == SegmentWindowCheck {
+			return entity.SegmentInfo{}, fmt.Errorf("%s: %w", op, entity.ErrInvalidSegmentWindow)
+		}
+		return entity.SegmentInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return entity.SegmentInfo{}, fmt.Errorf("%s: %w", op, entity.ErrSegmentNotFound)
+	}
+
+	if upd.Prerequisites != nil {
+		if err := replacePrerequisites(tx, slug, upd.Prerequisites); err != nil {
+			return entity.SegmentInfo{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	err = tx.Commit(context.TODO())
+	if err != nil {
+		return entity.SegmentInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r.Segment(slug)
+}
+
+// Returns the audit trail of the segment, oldest changes first
+func (r *SegmentRepository) SegmentChanges(slug string) ([]entity.SegmentChange, error) {
+	op := "repo.pg.segment.SegmentChanges"
+
+	query := `
+	SELECT change_id, segment_slug, old_values, new_values, change_date
+	FROM segment_changes
+	WHERE segment_slug = $1
+	ORDER BY change_id
+	`
+	rows, err := r.db.Query(context.TODO(), query, slug)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	changes := []entity.SegmentChange{}
+	for rows.Next() {
+		var change entity.SegmentChange
+		if err := rows.Scan(
+			&change.ID,
+			&change.SegmentSlug,
+			&change.OldValues,
+			&change.NewValues,
+			&change.Date,
+		); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return changes, nil
+}
+
+// Replaces the global holdout, zero percent disables it
+func (r *SegmentRepository) SetHoldout(h entity.Holdout) error {
+	op := "repo.pg.segment.SetHoldout"
+
+	query := `
+	INSERT INTO holdout
+	(salt, percent)
+	VALUES ($1, $2)
+	ON CONFLICT (id) DO UPDATE SET salt = EXCLUDED.salt, percent = EXCLUDED.percent
+	`
+	if _, err := r.db.Exec(context.TODO(), query, h.Salt, h.Percent); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *SegmentRepository) Holdout() (entity.Holdout, error) {
+	op := "repo.pg.segment.Holdout"
+
+	h, err := holdout(r.db)
+	if err != nil {
+		return entity.Holdout{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return h, nil
+}
+
+var segmentSortColumns = map[entity.SegmentSort]string{
+	entity.SegmentSortSlug:    "s.slug",
+	entity.SegmentSortMembers: "members",
+}
+
+const segmentInfoColumns = `
+	s.slug, COALESCE(s.salt, ''), s.deterministic, COALESCE(s.layer, ''), COALESCE(s.targeting_rule, ''),
+	COALESCE(s.experiment_slug, ''), COALESCE(s.variant, ''), s.rollout_percent::INTEGER, s.rollout_expires_at,
+	s.description, s.owner, s.tags, s.created_at, s.updated_at, s.archived_at,
+	s.starts_at, s.ends_at, s.allow_holdout, s.removal_policy, s.max_members,
+	ARRAY(SELECT sp.prerequisite_slug FROM segment_prerequisites sp WHERE sp.segment_slug = s.slug ORDER BY 1),
+	(SELECT COUNT(*) FROM segments_to_users su WHERE su.segment_slug = s.slug AND su.expiration_date > NOW()) AS members
+	`
+
+// Returns a page of segments matching the filter and the total number of matching segments
+func (r *SegmentRepository) Segments(filter entity.SegmentFilter) ([]entity.SegmentInfo, int, error) {
+	op := "repo.pg.segment.Segments"
+
+	prefix := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(filter.Prefix) + "%"
+
+	query := `
+	SELECT COUNT(*) FROM segments
+	WHERE slug LIKE $1 AND (archived_at IS NOT NULL) = $2
+	`
+	var total int
+	if err := r.db.QueryRow(context.TODO(), query, prefix, filter.Archived).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	column, ok := segmentSortColumns[filter.Sort]
+	if !ok {
+		column = segmentSortColumns[entity.SegmentSortSlug]
+	}
+	direction := "ASC"
+	if filter.Desc {
+		direction = "DESC"
+	}
+
+	query = fmt.Sprintf(`
+	SELECT %s
+	FROM segments s
+	WHERE s.slug LIKE $1 AND (s.archived_at IS NOT NULL) = $2
+	ORDER BY %s %s, s.slug
+	LIMIT $3 OFFSET $4
+	`, segmentInfoColumns, column, direction)
+
+	rows, err := r.db.Query(context.TODO(), query, prefix, filter.Archived, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	segments := []entity.SegmentInfo{}
+	for rows.Next() {
+		seg, err := scanSegmentInfo(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", op, err)
+		}
+		segments = append(segments, seg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return segments, total, nil
+}
+
+func (r *SegmentRepository) Segment(slug string) (entity.SegmentInfo, error) {
+	op := "repo.pg.segment.Segment"
+
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM segments s
+	WHERE s.slug = $1
+	`, segmentInfoColumns)
+
+	seg, err := scanSegmentInfo(r.db.QueryRow(context.TODO(), query, slug))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.SegmentInfo{}, fmt.Errorf("%s: %w", op, entity.ErrSegmentNotFound)
+		}
+		return entity.SegmentInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return seg, nil
+}
+
+func scanSegmentInfo(row pgx.Row) (entity.SegmentInfo, error) {
+	var seg entity.SegmentInfo
+	var deterministic bool
+	var removalPolicy string
+	if err := row.Scan(
+		&seg.Slug,
+		&seg.Salt,
+		&deterministic,
+		&seg.Layer,
+		&seg.Rule,
+		&seg.Experiment,
+		&seg.Variant,
+		&seg.RolloutPercent,
+		&seg.RolloutExpiresAt,
+		&seg.Description,
+		&seg.Owner,
+		&seg.Tags,
+		&seg.CreatedAt,
+		&seg.UpdatedAt,
+		&seg.ArchivedAt,
+		&seg.StartsAt,
+		&seg.EndsAt,
+		&seg.AllowHoldout,
+		&removalPolicy,
+		&seg.MaxMembers,
+		&seg.Prerequisites,
+		&seg.Members,
+	); err != nil {
+		return entity.SegmentInfo{}, err
+	}
+	seg.RemovalPolicy = entity.RemovalPolicy(removalPolicy)
+
+	if seg.RolloutPercent != nil {
+		seg.AssignMode = entity.AssignModeRandom
+		if deterministic {
+			seg.AssignMode = entity.AssignModeHash
+		}
+	}
+
+	return seg, nil
+}
+
+// Tags column is NOT NULL, so missing tags are stored as an empty array
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// Returns the global holdout or an empty one if it was never set
+func holdout(db *pg.Postgres) (entity.Holdout, error) {
+	query := `
+	SELECT salt, percent::INTEGER FROM holdout
+	`
+	var h entity.Holdout
+	if err := db.QueryRow(context.TODO(), query).Scan(&h.Salt, &h.Percent); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entity.Holdout{}, nil
+		}
+		return entity.Holdout{}, err
+	}
+
+	return h, nil
+}
+
+// Replaces the prerequisites of the segment within the transaction. Prerequisites are added
+// under a transaction lock, so concurrent updates can not form a cycle together
+func replacePrerequisites(tx pgx.Tx, slug string, prerequisites []string) error {
+	if len(prerequisites) > 0 {
+		query := `
+		SELECT pg_advisory_xact_lock(hashtext('segment_prerequisites'))
+		`
+		if _, err := tx.Exec(context.TODO(), query); err != nil {
+			return err
+		}
+	}
+
+	query := `
+	DELETE FROM segment_prerequisites
+	WHERE segment_slug = $1
+	`
+	if _, err := tx.Exec(context.TODO(), query, slug); err != nil {
+		return err
+	}
+
+	query = `
+	INSERT INTO segment_prerequisites
+	(segment_slug, prerequisite_slug)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING
+	`
+	for _, prerequisite := range prerequisites {
+		if _, err := tx.Exec(context.TODO(), query, slug, prerequisite); err != nil {
+			var pgErr *pgconn.PgError
+			if ok := errors.As(err, &pgErr); ok && pgErr.ConstraintName == InvalidPrerequisiteFK {
+				return fmt.Errorf("%s: %w", prerequisite, entity.ErrPrerequisiteNotFound)
+			}
+			return err
+		}
+	}
+
+	if len(prerequisites) == 0 {
+		return nil
+	}
+
+	// the graph was acyclic before, so a new cycle has to pass through the segment
+	query = `
+	WITH RECURSIVE reachable(slug) AS (
+		SELECT prerequisite_slug FROM segment_prerequisites WHERE segment_slug = $1
+		UNION
+		SELECT sp.prerequisite_slug FROM segment_prerequisites sp
+		JOIN reachable r ON r.slug = sp.segment_slug
+	)
+	SELECT EXISTS (SELECT 1 FROM reachable WHERE slug = $1)
+	`
+	var cycle bool
+	if err := tx.QueryRow(context.TODO(), query, slug).Scan(&cycle); err != nil {
+		return err
+	}
+	if cycle {
+		return entity.ErrPrerequisiteCycle
+	}
+
+	return nil
+}
+
+var setOperators = map[entity.SetOperation]string{
+	entity.SetUnion:        "UNION",
+	entity.SetIntersection: "INTERSECT",
+	entity.SetDifference:   "EXCEPT",
+}
+
+// Compiles the set expression into a query returning user IDs, slugs are passed as query arguments.
+// Every operand is parenthesized, so the operations keep the tree order regardless of SQL precedence
+func compileExpression(expr entity.SegmentExpression, args *[]any) string {
+	if expr.Op == "" {
+		*args = append(*args, expr.Slug)
+		return fmt.Sprintf(
+			"SELECT user_id FROM segments_to_users WHERE segment_slug = $%d AND expiration_date > NOW()",
+			len(*args))
+	}
+
+	operands := make([]string, len(expr.Args))
+	for i, arg := range expr.Args {
+		operands[i] = "(" + compileExpression(arg, args) + ")"
+	}
+	return strings.Join(operands, " "+setOperators[expr.Op]+" ")
+}
+
+// Returns the distinct segment slugs used by the expression
+func expressionSlugs(expr entity.SegmentExpression, seen map[string]bool) []string {
+	var slugs []string
+	if expr.Op == "" && !seen[expr.Slug] {
+		seen[expr.Slug] = true
+		slugs = append(slugs, expr.Slug)
+	}
+	for _, arg := range expr.Args {
+		slugs = append(slugs, expressionSlugs(arg, seen)...)
+	}
+	return slugs
+}
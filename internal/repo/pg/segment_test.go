@@ -0,0 +1,27 @@
+package pg
+
+import (
+	"testing"
+
+	"experiment.io/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateSegmentKeepsUpdatedAtWithoutChanges(t *testing.T) {
+	db := testPostgres(t)
+	r := NewSegmentRepository(db)
+
+	slug := testSegment(t, db, "UPDATED")
+	description := "checkout experiment"
+	changed, err := r.UpdateSegment(slug, entity.SegmentUpdate{Description: &description})
+	require.NoError(t, err)
+
+	unchanged, err := r.UpdateSegment(slug, entity.SegmentUpdate{Description: &description})
+	require.NoError(t, err)
+	require.Equal(t, changed.UpdatedAt, unchanged.UpdatedAt)
+
+	description = "checkout experiment v2"
+	updated, err := r.UpdateSegment(slug, entity.SegmentUpdate{Description: &description})
+	require.NoError(t, err)
+	require.True(t, updated.UpdatedAt.After(changed.UpdatedAt))
+}
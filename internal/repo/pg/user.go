@@ -13,11 +13,10 @@ import (
 	"experiment.io/pkg/storage/pg"
 	pgx "github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/lib/pq"
 )
 
-var MaxTime = time.Date(9999, time.December, 31, 23, 59, 59, 999999999, time.UTC)
-
 type UserRepository struct {
 	db            *pg.Postgres
 	dirToStoreCSV string
@@ -33,17 +32,29 @@ func NewUserRepository(db *pg.Postgres, dirToStoreCSV string) (*UserRepository,
 	return &UserRepository{db, dirToStoreCSV}, nil
 }
 
+// Creates a user and enrolls him into the percentage segments whose rollout rule matches
 func (r *UserRepository) NewUser(u entity.User) (int, error) {
 	op := "repo.pg.user.New"
 
+	tx, err := r.db.Begin(context.TODO())
+	defer tx.Rollback(context.TODO())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	attributes := u.Attributes
+	if attributes == nil {
+		attributes = map[string]any{}
+	}
+
 	query := `
 	INSERT INTO users
-	(name, encrypted_pwd) 
-	VALUES($1, $2)
+	(name, encrypted_pwd, attributes) 
+	VALUES($1, $2, $3)
 	RETURNING id
 	`
 	var id int
-	err := r.db.QueryRow(context.TODO(), query, u.Name, u.Password).Scan(&id)
+	err = tx.QueryRow(context.TODO(), query, u.Name, u.Password, attributes).Scan(&id)
 
 	if err != nil {
 		var pgErr *pgconn.PgError
@@ -53,6 +64,18 @@ func (r *UserRepository) NewUser(u entity.User) (int, error) {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
+	query = `
+	SELECT * FROM enroll_user_in_rollouts($1)
+	`
+	if _, err := tx.Exec(context.TODO(), query, id); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.Commit(context.TODO())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
 	return id, nil
 }
 
@@ -76,6 +99,98 @@ func (r *UserRepository) Password(username string) (string, error) {
 	return password, nil
 }
 
+// Returns the user attributes along with the registered_at attribute in RFC3339
+func (r *UserRepository) UserAttributes(userID int) (map[string]any, error) {
+	op := "repo.pg.user.UserAttributes"
+
+	query := `
+	SELECT attributes, created_at FROM users
+	WHERE id = $1
+	`
+	var attributes map[string]any
+	var createdAt time.Time
+	err := r.db.QueryRow(context.TODO(), query, userID).Scan(&attributes, &createdAt)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, entity.ErrUserNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if attributes == nil {
+		attributes = map[string]any{}
+	}
+	attributes["registered_at"] = createdAt.UTC().Format(time.RFC3339)
+
+	return attributes, nil
+}
+
+func (r *UserRepository) SetUserAttributes(userID int, attributes map[string]any) error {
+	op := "repo.pg.user.SetUserAttributes"
+
+	if attributes == nil {
+		attributes = map[string]any{}
+	}
+
+	query := `
+	UPDATE users
+	SET attributes = $2
+	WHERE id = $1
+	`
+	res, err := r.db.Exec(context.TODO(), query, userID, attributes)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, entity.ErrUserNotFound)
+	}
+
+	return nil
+}
+
+// Returns the active segments within their activation window that have a targeting rule
+func (r *UserRepository) SegmentRules() ([]entity.Segment, error) {
+	op := "repo.pg.user.SegmentRules"
+
+	query := `
+	SELECT slug, targeting_rule, allow_holdout FROM segments
+	WHERE targeting_rule IS NOT NULL AND archived_at IS NULL
+	  AND (starts_at IS NULL OR starts_at <= NOW()) AND (ends_at IS NULL OR ends_at > NOW())
+	`
+
+	rows, err := r.db.Query(context.TODO(), query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var segments []entity.Segment
+	for rows.Next() {
+		var seg entity.Segment
+		if err := rows.Scan(&seg.Slug, &seg.Rule, &seg.AllowHoldout); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		segments = append(segments, seg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return segments, nil
+}
+
+func (r *UserRepository) Holdout() (entity.Holdout, error) {
+	op := "repo.pg.user.Holdout"
+
+	h, err := holdout(r.db)
+	if err != nil {
+		return entity.Holdout{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return h, nil
+}
+
 // TODO : Remove the loop and enter everything in one big request
 
 // Adds expire time only if ttl > 0, otherwise make it infinity
@@ -95,7 +210,7 @@ func (r *UserRepository) AddUserSegments(userID int, added []entity.SlugWithExpi
 	`
 	for _, segmentToAdd := range added {
 		if _, err := tx.Exec(context.TODO(), query, segmentToAdd.Slug, userID, segmentToAdd.ExpiredDate); err != nil {
-			return r.checkUserToSegmentError(op, err)
+			return checkUserToSegmentError(op, err)
 		}
 	}
 
@@ -107,8 +222,13 @@ func (r *UserRepository) AddUserSegments(userID int, added []entity.SlugWithExpi
 	return nil
 }
 
-func (r *UserRepository) RemoveUserSegments(userID int, removed []string) error {
-	op := "repo.pg.user.RemoveUserSegments"
+// Sets the expiration of the active memberships, the segments the user is not an active member of are added.
+// An expired membership is revived in place, the revival guard checks the archived, holdout, prerequisite,
+// variant, layer and capacity rules for it and the memberships depending on it are kept.
+// Memberships of archived segments are not extended.
+// Expiration in the past makes the membership infinite like on adding
+func (r *UserRepository) UpdateUserSegments(userID int, updated []entity.SlugWithExpiredDate) error {
+	op := "repo.pg.user.UpdateUserSegments"
 
 	tx, err := r.db.Begin(context.TODO())
 	defer tx.Rollback(context.TODO())
@@ -116,18 +236,41 @@ func (r *UserRepository) RemoveUserSegments(userID int, removed []string) error
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	query := `
-	DELETE FROM segments_to_users
-	WHERE user_id = $1 AND segment_slug = $2
+	archivedQuery := `
+	SELECT archived_at IS NOT NULL FROM segments
+	WHERE slug = $1
+	`
+	updateQuery := `
+	UPDATE segments_to_users
+	SET expiration_date = CASE WHEN $3 > NOW() THEN $3 ELSE 'infinity' END
+	WHERE segment_slug = $1 AND user_id = $2
+	`
+	insertQuery := `
+	INSERT INTO segments_to_users
+	(segment_slug, user_id, expiration_date)
+	VALUES ($1, $2, CASE WHEN $3 > NOW() THEN $3 ELSE 'infinity' END)
 	`
-	for _, segmentToRemove := range removed {
+	for _, segmentToUpdate := range updated {
+		var archived bool
+		if err := tx.QueryRow(context.TODO(), archivedQuery, segmentToUpdate.Slug).Scan(&archived); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("%s: %w", op, entity.ErrSegmentNotFound)
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if archived {
+			return fmt.Errorf("%s: %w", op, entity.ErrSegmentArchived)
+		}
 
-		res, err := tx.Exec(context.TODO(), query, userID, segmentToRemove)
+		res, err := tx.Exec(context.TODO(), updateQuery, segmentToUpdate.Slug, userID, segmentToUpdate.ExpiredDate)
 		if err != nil {
-			return fmt.Errorf("%s: %w", op, err)
+			return checkUserToSegmentError(op, err)
 		}
-		if res.RowsAffected() == 0 {
-			return fmt.Errorf("%s: %w", op, entity.ErrUserToSegmentNotFound)
+		if res.RowsAffected() > 0 {
+			continue
+		}
+		if _, err := tx.Exec(context.TODO(), insertQuery, segmentToUpdate.Slug, userID, segmentToUpdate.ExpiredDate); err != nil {
+			return checkUserToSegmentError(op, err)
 		}
 	}
 
@@ -139,12 +282,160 @@ func (r *UserRepository) RemoveUserSegments(userID int, removed []string) error
 	return nil
 }
 
+// Removes the memberships in one statement, the removal policy trigger removes the dependent memberships
+// with the cascade policy and fails if a dependent membership has the block policy
+func (r *UserRepository) RemoveUserSegments(userID int, removed []string) error {
+	op := "repo.pg.user.RemoveUserSegments"
+
+	query := `
+	DELETE FROM segments_to_users
+	WHERE user_id = $1 AND segment_slug = ANY($2::VARCHAR[])
+	`
+	res, err := r.db.Exec(context.TODO(), query, userID, removed)
+	if err != nil {
+		return checkUserToSegmentError(op, err)
+	}
+	if int(res.RowsAffected()) < len(removed) {
+		return fmt.Errorf("%s: %w", op, entity.ErrUserToSegmentNotFound)
+	}
+
+	return nil
+}
+
+// Removes at most batchSize expired memberships, the audit trigger records them as expired.
+// Rows locked by concurrent transactions are skipped until the next batch
+func (r *UserRepository) RemoveExpiredMemberships(batchSize int) (int, error) {
+	op := "repo.pg.user.RemoveExpiredMemberships"
+
+	query := `
+	DELETE FROM segments_to_users su
+	USING (
+		SELECT segment_slug, user_id FROM segments_to_users
+		WHERE expiration_date <= NOW()
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	) expired
+	WHERE su.segment_slug = expired.segment_slug AND su.user_id = expired.user_id
+	`
+	res, err := r.db.Exec(context.TODO(), query, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return int(res.RowsAffected()), nil
+}
+
+// Returns at most limit memberships of active segments expiring until the given time
+// that have not been notified about their current expiration, the earliest first.
+// The skipped memberships and the ones whose delivery failed maxAttempts times are not returned
+func (r *UserRepository) ExpiringMemberships(until time.Time, skipped []entity.ExpiryNotification, maxAttempts, limit int) ([]entity.ExpiryNotification, error) {
+	op := "repo.pg.user.ExpiringMemberships"
+
+	slugs := make([]string, 0, len(skipped))
+	userIDs := make([]int, 0, len(skipped))
+	dates := make([]time.Time, 0, len(skipped))
+	for _, n := range skipped {
+		slugs = append(slugs, n.SegmentSlug)
+		userIDs = append(userIDs, n.UserID)
+		dates = append(dates, n.ExpiredDate)
+	}
+
+	query := `
+	SELECT su.segment_slug, su.user_id, su.expiration_date
+	FROM segments_to_users su
+	JOIN segments s ON s.slug = su.segment_slug
+	WHERE su.expiration_date > NOW() AND su.expiration_date <= $1 AND s.archived_at IS NULL
+	  AND NOT EXISTS (
+		SELECT 1 FROM expiry_notifications n
+		WHERE n.segment_slug = su.segment_slug AND n.user_id = su.user_id AND n.expiration_date = su.expiration_date
+	  )
+	  AND NOT EXISTS (
+		SELECT 1 FROM expiry_notification_failures f
+		WHERE f.segment_slug = su.segment_slug AND f.user_id = su.user_id AND f.expiration_date = su.expiration_date
+		  AND f.attempts >= $5
+	  )
+	  AND (su.segment_slug, su.user_id, su.expiration_date) NOT IN (
+		SELECT * FROM unnest($2::TEXT[], $3::INTEGER[], $4::TIMESTAMPTZ[])
+	  )
+	ORDER BY su.expiration_date
+	LIMIT $6
+	`
+	rows, err := r.db.Query(context.TODO(), query, until, slugs, userIDs, dates, maxAttempts, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var expiring []entity.ExpiryNotification
+	for rows.Next() {
+		var n entity.ExpiryNotification
+		if err := rows.Scan(&n.SegmentSlug, &n.UserID, &n.ExpiredDate); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		expiring = append(expiring, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return expiring, nil
+}
+
+// Records the notification, memberships removed in the meantime are skipped
+func (r *UserRepository) MarkExpiryNotified(n entity.ExpiryNotification) error {
+	op := "repo.pg.user.MarkExpiryNotified"
+
+	query := `
+	INSERT INTO expiry_notifications
+	(segment_slug, user_id, expiration_date)
+	VALUES ($1, $2, $3)
+	ON CONFLICT DO NOTHING
+	`
+	if _, err := r.db.Exec(context.TODO(), query, n.SegmentSlug, n.UserID, n.ExpiredDate); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == NonExistentFKErrCode {
+			return nil
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Records a failed delivery of the notification and counts the attempts,
+// memberships removed in the meantime are skipped
+func (r *UserRepository) MarkExpiryFailed(n entity.ExpiryNotification, reason string) error {
+	op := "repo.pg.user.MarkExpiryFailed"
+
+	query := `
+	INSERT INTO expiry_notification_failures
+	(segment_slug, user_id, expiration_date, last_error)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (segment_slug, user_id, expiration_date) DO UPDATE SET
+	attempts = expiry_notification_failures.attempts + 1,
+	last_error = EXCLUDED.last_error,
+	failed_at = NOW()
+	`
+	if _, err := r.db.Exec(context.TODO(), query, n.SegmentSlug, n.UserID, n.ExpiredDate, reason); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == NonExistentFKErrCode {
+			return nil
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
 func (r *UserRepository) UserSegments(userID int) ([]entity.SlugWithExpiredDate, error) {
 	op := "repo.pg.user.UserSegments"
 
 	query := `
-	SELECT segment_slug, expiration_date FROM segments_to_users
-	WHERE user_id = $1 AND expiration_date > NOW()
+	SELECT su.segment_slug, su.expiration_date, s.experiment_slug, s.variant
+	FROM segments_to_users su
+	JOIN segments s ON s.slug = su.segment_slug
+	WHERE su.user_id = $1 AND su.expiration_date > NOW() AND s.archived_at IS NULL
+	  AND (s.starts_at IS NULL OR s.starts_at <= NOW()) AND (s.ends_at IS NULL OR s.ends_at > NOW())
 	`
 
 	rows, err := r.db.Query(context.TODO(), query, userID)
@@ -157,9 +448,12 @@ func (r *UserRepository) UserSegments(userID int) ([]entity.SlugWithExpiredDate,
 	for rows.Next() {
 		var seg entity.SlugWithExpiredDate
 		var expirationDate pq.NullTime // needed in order to scan infinity time
+		var experiment, variant *string
 		if err := rows.Scan(
 			&seg.Slug,
 			&expirationDate,
+			&experiment,
+			&variant,
 		); err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
@@ -167,7 +461,11 @@ func (r *UserRepository) UserSegments(userID int) ([]entity.SlugWithExpiredDate,
 		if expirationDate.Valid {
 			seg.ExpiredDate = expirationDate.Time
 		} else {
-			seg.ExpiredDate = MaxTime // set max allowed time if expired time is infinite
+			seg.ExpiredDate = entity.MaxTime // set max allowed time if expired time is infinite
+		}
+		if experiment != nil && variant != nil {
+			seg.Experiment = *experiment
+			seg.Variant = *variant
 		}
 
 		segments = append(segments, seg)
@@ -176,47 +474,158 @@ func (r *UserRepository) UserSegments(userID int) ([]entity.SlugWithExpiredDate,
 	return segments, nil
 }
 
-func (r *UserRepository) UsersHistoryInByDate(year int, month int) ([]entity.UserSegmentsHistory, error) {
-	op := "repo.pg.user.UsersHistoryInByDate"
+// Returns the operations matching the filter ordered by operation_id
+func (r *UserRepository) UsersHistory(filter entity.HistoryFilter) ([]entity.UserSegmentsHistory, error) {
+	op := "repo.pg.user.UsersHistory"
 
-	firstDay := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
-	lastDay := firstDay.AddDate(0, 1, 0)
+	operations := make([]string, len(filter.Operations))
+	for i, operation := range filter.Operations {
+		operations[i] = string(operation)
+	}
 
 	query := `
-	SELECT operation_id, user_id, segment_slug, isAdded, operation_date
+	SELECT operation_id, user_id, segment_slug, isAdded, operation, operation_date, expiration_date
 	FROM segment_user_operations
-	WHERE operation_date >= $1 AND operation_date <= $2
+	WHERE operation_date >= $1 AND operation_date < $2
+	  AND ($3::INT IS NULL OR user_id = $3)
+	  AND ($4::VARCHAR = '' OR segment_slug = $4)
+	  AND (cardinality($5::VARCHAR[]) = 0 OR operation = ANY($5::VARCHAR[]))
+	ORDER BY operation_id
 	`
 
-	rows, err := r.db.Query(context.TODO(), query, firstDay, lastDay)
+	rows, err := r.db.Query(context.TODO(), query, filter.From, filter.To, filter.UserID, filter.SegmentSlug, operations)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+
+	history, err := scanHistory(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return history, nil
+}
+
+// Returns at most limit operations of the user with operation_id greater than afterID, ordered by operation_id
+func (r *UserRepository) UserHistory(userID int, afterID int, limit int) ([]entity.UserSegmentsHistory, error) {
+	op := "repo.pg.user.UserHistory"
+
+	query := `
+	SELECT operation_id, user_id, segment_slug, isAdded, operation, operation_date, expiration_date
+	FROM segment_user_operations
+	WHERE user_id = $1 AND operation_id > $2
+	ORDER BY operation_id
+	LIMIT $3
+	`
+	rows, err := r.db.Query(context.TODO(), query, userID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	history, err := scanHistory(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// the history of a deleted user is kept, so only the first page of an unknown user is an error
+	if len(history) == 0 && afterID == 0 {
+		exists, err := r.userExists(userID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("%s: %w", op, entity.ErrUserNotFound)
+		}
+	}
+
+	return history, nil
+}
+
+// Returns the last operation of the user in every segment that happened at or before the given time.
+// Operations are ordered by operation_date, since expired operations are recorded after they happened
+func (r *UserRepository) LastUserOperations(userID int, at time.Time) ([]entity.UserSegmentsHistory, error) {
+	op := "repo.pg.user.LastUserOperations"
+
+	query := `
+	SELECT DISTINCT ON (segment_slug)
+		operation_id, user_id, segment_slug, isAdded, operation, operation_date, expiration_date
+	FROM segment_user_operations
+	WHERE user_id = $1 AND operation_date <= $2
+	ORDER BY segment_slug, operation_date DESC, operation_id DESC
+	`
+	rows, err := r.db.Query(context.TODO(), query, userID, at)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	history, err := scanHistory(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(history) == 0 {
+		exists, err := r.userExists(userID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("%s: %w", op, entity.ErrUserNotFound)
+		}
+	}
+
+	return history, nil
+}
+
+func (r *UserRepository) userExists(userID int) (bool, error) {
+	query := `
+	SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)
+	`
+	var exists bool
+	if err := r.db.QueryRow(context.TODO(), query, userID).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func scanHistory(rows pgx.Rows) ([]entity.UserSegmentsHistory, error) {
 	defer rows.Close()
 
 	var history []entity.UserSegmentsHistory
 	for rows.Next() {
 		var hist entity.UserSegmentsHistory
+		var expirationDate pgtype.Timestamptz // needed in order to scan infinity time
 
 		if err := rows.Scan(
 			&hist.OperationID,
 			&hist.UserID,
 			&hist.SegmentSlug,
 			&hist.IsAdded,
+			&hist.Operation,
 			&hist.Date,
+			&expirationDate,
 		); err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
+			return nil, err
+		}
+
+		switch {
+		case !expirationDate.Valid:
+		case expirationDate.InfinityModifier == pgtype.Infinity:
+			maxTime := entity.MaxTime // set max allowed time if expired time is infinite
+			hist.ExpirationDate = &maxTime
+		default:
+			hist.ExpirationDate = &expirationDate.Time
 		}
 
 		history = append(history, hist)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
 	return history, nil
 }
 
-func (r *UserRepository) WriteHistoryToCSV(history []entity.UserSegmentsHistory, year int, month int) (string, error) {
+func (r *UserRepository) WriteHistoryToCSV(history []entity.UserSegmentsHistory, name string) (string, error) {
 	op := "usecase.user.WriteHistoryToCSV"
-	filePath := fmt.Sprintf("%s/user_segments_history-%d-%d.csv", r.dirToStoreCSV, year, month)
+	filePath := fmt.Sprintf("%s/%s.csv", r.dirToStoreCSV, name)
 	file, err := os.Create(filePath)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
@@ -226,14 +635,14 @@ func (r *UserRepository) WriteHistoryToCSV(history []entity.UserSegmentsHistory,
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
-	header := []string{"operation_id", "user_id", "segment_slug", "is_added", "date"}
+	header := []string{"operation_id", "user_id", "segment_slug", "is_added", "operation", "date"}
 	if err = writer.Write(header); err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
 	var rows [][]string
 	for _, h := range history {
-		row := []string{strconv.Itoa(h.OperationID), strconv.Itoa(h.UserID), h.SegmentSlug, strconv.FormatBool(h.IsAdded), h.Date.Format(time.RFC3339)}
+		row := []string{strconv.Itoa(h.OperationID), strconv.Itoa(h.UserID), h.SegmentSlug, strconv.FormatBool(h.IsAdded), string(h.Operation), h.Date.Format(time.RFC3339)}
 		rows = append(rows, row)
 	}
 	fmt.Println(rows)
@@ -244,9 +653,11 @@ func (r *UserRepository) WriteHistoryToCSV(history []entity.UserSegmentsHistory,
 	return filePath, nil
 }
 
-func (r *UserRepository) checkUserToSegmentError(op string, err error) error {
+func checkUserToSegmentError(op string, err error) error {
 	var pgErr *pgconn.PgError
-	errors.As(err, &pgErr)
+	if !errors.As(err, &pgErr) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 	switch {
 	case pgErr.Code == NonExistentFKErrCode && pgErr.ConstraintName == InvalidSegmentFK:
 		err = entity.ErrSegmentNotFound
@@ -254,6 +665,20 @@ func (r *UserRepository) checkUserToSegmentError(op string, err error) error {
 		err = entity.ErrUserNotFound
 	case pgErr.Code == DuplicatePKErrCode:
 		err = entity.ErrUserAlreadyAssigned
+	case pgErr.Code == VariantConflictErrCode:
+		err = entity.ErrVariantConflict
+	case pgErr.Code == LayerConflictErrCode:
+		err = entity.ErrLayerConflict
+	case pgErr.Code == SegmentArchivedErrCode:
+		err = entity.ErrSegmentArchived
+	case pgErr.Code == HoldoutErrCode:
+		err = entity.ErrUserInHoldout
+	case pgErr.Code == PrerequisiteErrCode:
+		err = entity.ErrPrerequisiteMissing
+	case pgErr.Code == SegmentFullErrCode:
+		err = entity.ErrSegmentFull
+	case pgErr.Code == PrerequisiteRequiredErrCode:
+		err = entity.ErrPrerequisiteRequired
 	}
 	return fmt.Errorf("%s: %w", op, err)
 }
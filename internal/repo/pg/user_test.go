@@ -0,0 +1,73 @@
+package pg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"experiment.io/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateUserSegmentsRevivesExpiredPrerequisite(t *testing.T) {
+	db := testPostgres(t)
+	r, err := NewUserRepository(db, t.TempDir())
+	require.NoError(t, err)
+
+	userID := testUser(t, db)
+	prerequisite := testSegment(t, db, "PREREQUISITE")
+	dependent := testSegment(t, db, "DEPENDENT")
+	_, err = db.Exec(context.TODO(), `
+	INSERT INTO segment_prerequisites (segment_slug, prerequisite_slug) VALUES ($1, $2)
+	`, dependent, prerequisite)
+	require.NoError(t, err)
+
+	_, err = db.Exec(context.TODO(), `
+	INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+	VALUES ($1, $3, NOW() + INTERVAL '1 second'), ($2, $3, 'infinity')
+	`, prerequisite, dependent, userID)
+	require.NoError(t, err)
+	time.Sleep(1100 * time.Millisecond)
+
+	err = r.UpdateUserSegments(userID, []entity.SlugWithExpiredDate{
+		{Slug: prerequisite, ExpiredDate: time.Now().Add(24 * time.Hour)},
+	})
+	require.NoError(t, err)
+
+	var members int
+	err = db.QueryRow(context.TODO(), `
+	SELECT COUNT(*) FROM segments_to_users
+	WHERE user_id = $1 AND expiration_date > NOW()
+	`, userID).Scan(&members)
+	require.NoError(t, err)
+	require.Equal(t, 2, members)
+}
+
+func TestAddUserSegmentsIgnoresArchivedSegmentOfLayer(t *testing.T) {
+	db := testPostgres(t)
+	r, err := NewUserRepository(db, t.TempDir())
+	require.NoError(t, err)
+
+	userID := testUser(t, db)
+	archived := testSegment(t, db, "ARCHIVED")
+	expired := testSegment(t, db, "EXPIRED")
+	added := testSegment(t, db, "ADDED")
+	layer := added
+	_, err = db.Exec(context.TODO(), `
+	UPDATE segments SET layer = $1 WHERE slug IN ($2, $3, $4)
+	`, layer, archived, expired, added)
+	require.NoError(t, err)
+
+	_, err = db.Exec(context.TODO(), `
+	INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+	VALUES ($1, $3, NOW() - INTERVAL '1 second'), ($2, $3, 'infinity')
+	`, expired, archived, userID)
+	require.NoError(t, err)
+	_, err = db.Exec(context.TODO(), `UPDATE segments SET archived_at = NOW() WHERE slug = $1`, archived)
+	require.NoError(t, err)
+
+	err = r.AddUserSegments(userID, []entity.SlugWithExpiredDate{
+		{Slug: added, ExpiredDate: time.Now().Add(24 * time.Hour)},
+	})
+	require.NoError(t, err)
+}
@@ -32,8 +32,9 @@ func (uc *AuthUsecase) Registration(user entity.User) (int, error) {
 	}
 
 	id, err := uc.r.NewUser(entity.User{
-		Name:     user.Name,
-		Password: hashedPass,
+		Name:       user.Name,
+		Password:   hashedPass,
+		Attributes: user.Attributes,
 	})
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"fmt"
+
+	"experiment.io/internal/entity"
+)
+
+type ExperimentRepo interface {
+	NewExperiment(exp entity.Experiment) (map[string][]int, error)
+	Experiment(slug string) (entity.Experiment, error)
+}
+
+type ExperimentUsecase struct {
+	r ExperimentRepo
+}
+
+func NewExperimentUsecase(r ExperimentRepo) *ExperimentUsecase {
+	return &ExperimentUsecase{r}
+}
+
+// Creates an experiment and returns the user IDs assigned to every variant.
+// Variant segments are named <experiment>_<variant>, the experiment slug is used as a salt unless another one is given
+func (uc *ExperimentUsecase) NewExperiment(exp entity.Experiment) (map[string][]int, error) {
+	op := "usecase.experiment.New"
+
+	if len(exp.Variants) < 2 {
+		return nil, fmt.Errorf("%s: %w", op, entity.ErrInvalidExperiment)
+	}
+
+	variants := make([]entity.Variant, len(exp.Variants))
+	copy(variants, exp.Variants)
+	exp.Variants = variants
+
+	names := make(map[string]struct{}, len(exp.Variants))
+	for i, v := range exp.Variants {
+		if _, ok := names[v.Name]; ok || v.Name == "" || v.Weight <= 0 {
+			return nil, fmt.Errorf("%s: %w", op, entity.ErrInvalidExperiment)
+		}
+		names[v.Name] = struct{}{}
+		exp.Variants[i].Segment = fmt.Sprintf("%s_%s", exp.Slug, v.Name)
+	}
+
+	if exp.Salt == "" {
+		exp.Salt = exp.Slug
+	}
+
+	assigned, err := uc.r.NewExperiment(exp)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return assigned, nil
+}
+
+func (uc *ExperimentUsecase) Experiment(slug string) (entity.Experiment, error) {
+	op := "usecase.experiment.Experiment"
+
+	exp, err := uc.r.Experiment(slug)
+	if err != nil {
+		return entity.Experiment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return exp, nil
+}
@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"testing"
+
+	"experiment.io/internal/entity"
+	"experiment.io/internal/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewExperiment(t *testing.T) {
+	r := new(mocks.ExperimentRepo)
+	uc := NewExperimentUsecase(r)
+
+	testCases := []struct {
+		name           string
+		experiment     entity.Experiment
+		repoExperiment entity.Experiment
+		repoAssigned   map[string][]int
+		repoErr        error
+		expected       map[string][]int
+		expectedErr    error
+	}{
+		{
+			name: "Success",
+			experiment: entity.Experiment{
+				Slug:     "CHECKOUT",
+				Variants: []entity.Variant{{Name: "control", Weight: 50}, {Name: "treatment", Weight: 50}},
+			},
+			repoExperiment: entity.Experiment{
+				Slug: "CHECKOUT",
+				Salt: "CHECKOUT",
+				Variants: []entity.Variant{
+					{Name: "control", Weight: 50, Segment: "CHECKOUT_control"},
+					{Name: "treatment", Weight: 50, Segment: "CHECKOUT_treatment"},
+				},
+			},
+			repoAssigned: map[string][]int{"control": {1}, "treatment": {2}},
+			repoErr:      nil,
+			expected:     map[string][]int{"control": {1}, "treatment": {2}},
+			expectedErr:  nil,
+		},
+		{
+			name: "Single variant",
+			experiment: entity.Experiment{
+				Slug:     "CHECKOUT",
+				Variants: []entity.Variant{{Name: "control", Weight: 50}},
+			},
+			expected:    nil,
+			expectedErr: entity.ErrInvalidExperiment,
+		},
+		{
+			name: "Duplicate variant names",
+			experiment: entity.Experiment{
+				Slug:     "CHECKOUT",
+				Variants: []entity.Variant{{Name: "control", Weight: 50}, {Name: "control", Weight: 50}},
+			},
+			expected:    nil,
+			expectedErr: entity.ErrInvalidExperiment,
+		},
+		{
+			name: "Zero weight",
+			experiment: entity.Experiment{
+				Slug:     "CHECKOUT",
+				Variants: []entity.Variant{{Name: "control", Weight: 50}, {Name: "treatment", Weight: 0}},
+			},
+			expected:    nil,
+			expectedErr: entity.ErrInvalidExperiment,
+		},
+		{
+			name: "Experiment already exists",
+			experiment: entity.Experiment{
+				Slug:     "CHECKOUT",
+				Salt:     "salt",
+				Variants: []entity.Variant{{Name: "control", Weight: 50}, {Name: "treatment", Weight: 50}},
+			},
+			repoExperiment: entity.Experiment{
+				Slug: "CHECKOUT",
+				Salt: "salt",
+				Variants: []entity.Variant{
+					{Name: "control", Weight: 50, Segment: "CHECKOUT_control"},
+					{Name: "treatment", Weight: 50, Segment: "CHECKOUT_treatment"},
+				},
+			},
+			repoAssigned: nil,
+			repoErr:      entity.ErrExperimentAlreadyExist,
+			expected:     nil,
+			expectedErr:  entity.ErrExperimentAlreadyExist,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("NewExperiment", tc.repoExperiment).Return(tc.repoAssigned, tc.repoErr)
+
+			assigned, err := uc.NewExperiment(tc.experiment)
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expected, assigned)
+
+			mockCall.Unset()
+		})
+	}
+}
+
+func TestExperiment(t *testing.T) {
+	r := new(mocks.ExperimentRepo)
+	uc := NewExperimentUsecase(r)
+
+	testCases := []struct {
+		name        string
+		slug        string
+		repoExp     entity.Experiment
+		repoErr     error
+		expectedErr error
+	}{
+		{
+			name:        "Success",
+			slug:        "CHECKOUT",
+			repoExp:     entity.Experiment{Slug: "CHECKOUT"},
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name:        "Non-existent experiment",
+			slug:        "CHECKOUT",
+			repoExp:     entity.Experiment{},
+			repoErr:     entity.ErrExperimentNotFound,
+			expectedErr: entity.ErrExperimentNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("Experiment", tc.slug).Return(tc.repoExp, tc.repoErr)
+
+			exp, err := uc.Experiment(tc.slug)
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.repoExp, exp)
+
+			mockCall.Unset()
+		})
+	}
+}
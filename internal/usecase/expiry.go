@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"experiment.io/internal/entity"
+)
+
+const defaultExpiryBatchSize = 1000
+
+type ExpiryRepo interface {
+	RemoveExpiredMemberships(batchSize int) (int, error)
+}
+
+type ExpiryUsecase struct {
+	r         ExpiryRepo
+	batchSize int
+
+	mu      sync.Mutex
+	lastRun entity.ExpiryRun
+}
+
+func NewExpiryUsecase(r ExpiryRepo, batchSize int) *ExpiryUsecase {
+	if batchSize <= 0 {
+		batchSize = defaultExpiryBatchSize
+	}
+	return &ExpiryUsecase{r: r, batchSize: batchSize}
+}
+
+// Removes expired memberships batch by batch until a batch is not full
+// and returns the number of removed memberships. The run is kept as the last run status
+func (uc *ExpiryUsecase) RemoveExpiredMemberships() (int, error) {
+	op := "usecase.expiry.RemoveExpiredMemberships"
+
+	run := entity.ExpiryRun{StartedAt: time.Now()}
+	var err error
+	for {
+		var removed int
+		removed, err = uc.r.RemoveExpiredMemberships(uc.batchSize)
+		run.Removed += removed
+		if err != nil {
+			err = fmt.Errorf("%s: %w", op, err)
+			break
+		}
+		if removed < uc.batchSize {
+			break
+		}
+	}
+	run.FinishedAt = time.Now()
+	run.Err = err
+
+	uc.mu.Lock()
+	run.TotalRemoved = uc.lastRun.TotalRemoved + run.Removed
+	uc.lastRun = run
+	uc.mu.Unlock()
+
+	return run.Removed, err
+}
+
+// Returns the last run, zero before the first one
+func (uc *ExpiryUsecase) LastExpiryRun() entity.ExpiryRun {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	return uc.lastRun
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"testing"
+
+	"experiment.io/internal/entity"
+	"experiment.io/internal/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveExpiredMemberships(t *testing.T) {
+	testCase := []struct {
+		name            string
+		batches         []int
+		repoErr         error
+		expectedRemoved int
+		expectedCalls   int
+		expectedErr     error
+	}{
+		{
+			name:            "Nothing expired",
+			batches:         []int{0},
+			repoErr:         nil,
+			expectedRemoved: 0,
+			expectedCalls:   1,
+			expectedErr:     nil,
+		},
+		{
+			name:            "Partial batch",
+			batches:         []int{3},
+			repoErr:         nil,
+			expectedRemoved: 3,
+			expectedCalls:   1,
+			expectedErr:     nil,
+		},
+		{
+			name:            "Full batches are followed by the next one",
+			batches:         []int{10, 10, 4},
+			repoErr:         nil,
+			expectedRemoved: 24,
+			expectedCalls:   3,
+			expectedErr:     nil,
+		},
+		{
+			name:            "Repository error",
+			batches:         []int{0},
+			repoErr:         entity.ErrInternalServer,
+			expectedRemoved: 0,
+			expectedCalls:   1,
+			expectedErr:     entity.ErrInternalServer,
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			r := new(mocks.ExpiryRepo)
+			uc := NewExpiryUsecase(r, 10)
+
+			for _, removed := range tc.batches[:len(tc.batches)-1] {
+				r.On("RemoveExpiredMemberships", 10).Return(removed, nil).Once()
+			}
+			r.On("RemoveExpiredMemberships", 10).Return(tc.batches[len(tc.batches)-1], tc.repoErr).Once()
+
+			removed, err := uc.RemoveExpiredMemberships()
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expectedRemoved, removed)
+			r.AssertNumberOfCalls(t, "RemoveExpiredMemberships", tc.expectedCalls)
+
+			run := uc.LastExpiryRun()
+			require.Equal(t, tc.expectedRemoved, run.Removed)
+			require.Equal(t, tc.expectedRemoved, run.TotalRemoved)
+			require.ErrorIs(t, run.Err, tc.expectedErr)
+			require.False(t, run.StartedAt.IsZero())
+		})
+	}
+}
+
+func TestLastExpiryRun(t *testing.T) {
+	r := new(mocks.ExpiryRepo)
+	uc := NewExpiryUsecase(r, 10)
+
+	require.Equal(t, entity.ExpiryRun{}, uc.LastExpiryRun())
+
+	r.On("RemoveExpiredMemberships", 10).Return(4, nil).Once()
+	r.On("RemoveExpiredMemberships", 10).Return(2, nil).Once()
+	_, err := uc.RemoveExpiredMemberships()
+	require.NoError(t, err)
+	_, err = uc.RemoveExpiredMemberships()
+	require.NoError(t, err)
+
+	run := uc.LastExpiryRun()
+	require.Equal(t, 2, run.Removed)
+	require.Equal(t, 6, run.TotalRemoved)
+}
@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"fmt"
+	"time"
+
+	"experiment.io/internal/entity"
+)
+
+const (
+	defaultNotificationBatchSize = 100
+	// a membership is not notified any more after this number of failed deliveries
+	maxNotificationAttempts = 5
+)
+
+type NotificationRepo interface {
+	ExpiringMemberships(until time.Time, skipped []entity.ExpiryNotification, maxAttempts, limit int) ([]entity.ExpiryNotification, error)
+	MarkExpiryNotified(n entity.ExpiryNotification) error
+	MarkExpiryFailed(n entity.ExpiryNotification, reason string) error
+}
+
+// Delivers notifications about expiring memberships, e.g. to a webhook
+type Notifier interface {
+	Notify(n entity.ExpiryNotification) error
+}
+
+type NotificationUsecase struct {
+	r         NotificationRepo
+	notifier  Notifier
+	window    time.Duration
+	batchSize int
+}
+
+func NewNotificationUsecase(r NotificationRepo, notifier Notifier, window time.Duration) *NotificationUsecase {
+	return &NotificationUsecase{r, notifier, window, defaultNotificationBatchSize}
+}
+
+// Notifies about the memberships expiring within the window and returns the number of sent notifications.
+// A membership is marked only after a successful delivery. A failed delivery is recorded and the run
+// continues with the others, the failed membership is skipped until the end of the run and retried
+// by the next runs up to maxNotificationAttempts.
+// Delivering and marking are not atomic: a membership is notified twice if marking fails after the delivery
+// or if several replicas run the notifier at the same time
+func (uc *NotificationUsecase) NotifyExpiringMemberships() (int, error) {
+	op := "usecase.notification.NotifyExpiringMemberships"
+
+	var sent int
+	var failed []entity.ExpiryNotification
+	var failure error
+	for {
+		expiring, err := uc.r.ExpiringMemberships(time.Now().Add(uc.window), failed, maxNotificationAttempts, uc.batchSize)
+		if err != nil {
+			return sent, fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, n := range expiring {
+			if err := uc.notifier.Notify(n); err != nil {
+				if err := uc.r.MarkExpiryFailed(n, err.Error()); err != nil {
+					return sent, fmt.Errorf("%s: %w", op, err)
+				}
+				failed = append(failed, n)
+				if failure == nil {
+					failure = fmt.Errorf("segment %s, user %d: %w", n.SegmentSlug, n.UserID, err)
+				}
+				continue
+			}
+			if err := uc.r.MarkExpiryNotified(n); err != nil {
+				return sent, fmt.Errorf("%s: %w", op, err)
+			}
+			sent++
+		}
+
+		if len(expiring) < uc.batchSize {
+			break
+		}
+	}
+
+	if len(failed) > 0 {
+		return sent, fmt.Errorf("%s: %d notifications failed, first: %w", op, len(failed), failure)
+	}
+	return sent, nil
+}
@@ -0,0 +1,191 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"experiment.io/internal/entity"
+	"experiment.io/internal/mocks"
+	"experiment.io/internal/notifier"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyExpiringMemberships(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	expiring := []entity.ExpiryNotification{
+		{SegmentSlug: "AVITO_DISCOUNT_30", UserID: 1, ExpiredDate: expiresAt},
+		{SegmentSlug: "AVITO_DISCOUNT_30", UserID: 2, ExpiredDate: expiresAt},
+	}
+
+	testCase := []struct {
+		name             string
+		expiring         []entity.ExpiryNotification
+		repoErr          error
+		markErr          error
+		expectedSent     int
+		expectedNotified int
+		expectedErr      error
+	}{
+		{
+			name:             "Notifies expiring memberships",
+			expiring:         expiring,
+			repoErr:          nil,
+			markErr:          nil,
+			expectedSent:     2,
+			expectedNotified: 2,
+			expectedErr:      nil,
+		},
+		{
+			name:             "Nothing expiring",
+			expiring:         nil,
+			repoErr:          nil,
+			markErr:          nil,
+			expectedSent:     0,
+			expectedNotified: 0,
+			expectedErr:      nil,
+		},
+		{
+			name:             "Repository error",
+			expiring:         nil,
+			repoErr:          entity.ErrInternalServer,
+			markErr:          nil,
+			expectedSent:     0,
+			expectedNotified: 0,
+			expectedErr:      entity.ErrInternalServer,
+		},
+		{
+			name:             "Stops when a notification can not be marked",
+			expiring:         expiring,
+			repoErr:          nil,
+			markErr:          entity.ErrInternalServer,
+			expectedSent:     0,
+			expectedNotified: 1,
+			expectedErr:      entity.ErrInternalServer,
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			r := new(mocks.NotificationRepo)
+			memory := notifier.NewMemory()
+			uc := NewNotificationUsecase(r, memory, 24*time.Hour)
+
+			r.On("ExpiringMemberships", mock.Anything, mock.Anything, maxNotificationAttempts, defaultNotificationBatchSize).Return(tc.expiring, tc.repoErr)
+			r.On("MarkExpiryNotified", mock.Anything).Return(tc.markErr)
+
+			sent, err := uc.NotifyExpiringMemberships()
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expectedSent, sent)
+			require.Len(t, memory.Notifications(), tc.expectedNotified)
+		})
+	}
+}
+
+func TestNotifyExpiringMembershipsWindow(t *testing.T) {
+	r := new(mocks.NotificationRepo)
+	uc := NewNotificationUsecase(r, notifier.NewMemory(), 24*time.Hour)
+
+	r.On("ExpiringMemberships", mock.MatchedBy(func(until time.Time) bool {
+		return until.After(time.Now().Add(23*time.Hour)) && until.Before(time.Now().Add(25*time.Hour))
+	}), mock.Anything, maxNotificationAttempts, defaultNotificationBatchSize).Return(nil, nil)
+
+	_, err := uc.NotifyExpiringMemberships()
+	require.NoError(t, err)
+	r.AssertExpectations(t)
+}
+
+func TestNotifyExpiringMembershipsFailedDelivery(t *testing.T) {
+	r := new(mocks.NotificationRepo)
+	n := new(mocks.Notifier)
+	uc := NewNotificationUsecase(r, n, 24*time.Hour)
+
+	expiring := []entity.ExpiryNotification{
+		{SegmentSlug: "AVITO_DISCOUNT_30", UserID: 1, ExpiredDate: time.Now().Add(time.Hour)},
+		{SegmentSlug: "AVITO_DISCOUNT_30", UserID: 2, ExpiredDate: time.Now().Add(time.Hour)},
+	}
+	r.On("ExpiringMemberships", mock.Anything, mock.Anything, maxNotificationAttempts, defaultNotificationBatchSize).Return(expiring, nil)
+	r.On("MarkExpiryFailed", expiring[0], entity.ErrNotificationRejected.Error()).Return(nil)
+	r.On("MarkExpiryNotified", expiring[1]).Return(nil)
+	n.On("Notify", expiring[0]).Return(entity.ErrNotificationRejected)
+	n.On("Notify", expiring[1]).Return(nil)
+
+	sent, err := uc.NotifyExpiringMemberships()
+	require.ErrorIs(t, err, entity.ErrNotificationRejected)
+	require.Equal(t, 1, sent)
+	r.AssertNotCalled(t, "MarkExpiryNotified", expiring[0])
+	r.AssertCalled(t, "MarkExpiryFailed", expiring[0], entity.ErrNotificationRejected.Error())
+}
+
+func TestNotifyExpiringMembershipsSkipsFailedInRun(t *testing.T) {
+	r := new(mocks.NotificationRepo)
+	n := new(mocks.Notifier)
+	uc := NewNotificationUsecase(r, n, 24*time.Hour)
+	uc.batchSize = 1
+
+	first := []entity.ExpiryNotification{
+		{SegmentSlug: "AVITO_DISCOUNT_30", UserID: 1, ExpiredDate: time.Now().Add(time.Hour)},
+	}
+	calls := 0
+	r.On("ExpiringMemberships", mock.Anything, mock.Anything, maxNotificationAttempts, 1).Return(
+		func(_ time.Time, skipped []entity.ExpiryNotification, _, _ int) ([]entity.ExpiryNotification, error) {
+			calls++
+			if calls > 3 {
+				return nil, entity.ErrInternalServer
+			}
+			if len(skipped) > 0 && skipped[0] == first[0] {
+				return nil, nil
+			}
+			return first, nil
+		})
+	r.On("MarkExpiryFailed", first[0], mock.Anything).Return(nil)
+	n.On("Notify", first[0]).Return(entity.ErrNotificationRejected)
+
+	sent, err := uc.NotifyExpiringMemberships()
+	require.ErrorIs(t, err, entity.ErrNotificationRejected)
+	require.Equal(t, 0, sent)
+	require.Equal(t, 2, calls)
+	r.AssertNumberOfCalls(t, "MarkExpiryFailed", 1)
+}
+
+func TestNotifyExpiringMembershipsFailureNotRecorded(t *testing.T) {
+	r := new(mocks.NotificationRepo)
+	n := new(mocks.Notifier)
+	uc := NewNotificationUsecase(r, n, 24*time.Hour)
+
+	expiring := []entity.ExpiryNotification{
+		{SegmentSlug: "AVITO_DISCOUNT_30", UserID: 1, ExpiredDate: time.Now().Add(time.Hour)},
+		{SegmentSlug: "AVITO_DISCOUNT_30", UserID: 2, ExpiredDate: time.Now().Add(time.Hour)},
+	}
+	r.On("ExpiringMemberships", mock.Anything, mock.Anything, maxNotificationAttempts, defaultNotificationBatchSize).Return(expiring, nil)
+	r.On("MarkExpiryFailed", expiring[0], mock.Anything).Return(entity.ErrInternalServer)
+	n.On("Notify", expiring[0]).Return(entity.ErrNotificationRejected)
+
+	sent, err := uc.NotifyExpiringMemberships()
+	require.ErrorIs(t, err, entity.ErrInternalServer)
+	require.Equal(t, 0, sent)
+	n.AssertNotCalled(t, "Notify", expiring[1])
+}
+
+func TestNotifyExpiringMembershipsBatches(t *testing.T) {
+	r := new(mocks.NotificationRepo)
+	memory := notifier.NewMemory()
+	uc := NewNotificationUsecase(r, memory, 24*time.Hour)
+	uc.batchSize = 2
+
+	first := []entity.ExpiryNotification{
+		{SegmentSlug: "AVITO_DISCOUNT_30", UserID: 1, ExpiredDate: time.Now().Add(time.Hour)},
+		{SegmentSlug: "AVITO_DISCOUNT_30", UserID: 2, ExpiredDate: time.Now().Add(time.Hour)},
+	}
+	second := []entity.ExpiryNotification{
+		{SegmentSlug: "AVITO_DISCOUNT_50", UserID: 1, ExpiredDate: time.Now().Add(2 * time.Hour)},
+	}
+	r.On("ExpiringMemberships", mock.Anything, mock.Anything, maxNotificationAttempts, 2).Return(first, nil).Once()
+	r.On("ExpiringMemberships", mock.Anything, mock.Anything, maxNotificationAttempts, 2).Return(second, nil).Once()
+	r.On("MarkExpiryNotified", mock.Anything).Return(nil)
+
+	sent, err := uc.NotifyExpiringMemberships()
+	require.NoError(t, err)
+	require.Equal(t, 3, sent)
+	require.Equal(t, append(first, second...), memory.Notifications())
+}
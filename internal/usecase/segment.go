@@ -2,16 +2,45 @@ package usecase
 
 import (
 	"fmt"
+	"sort"
+	"time"
 
 	"experiment.io/internal/entity"
+	"experiment.io/pkg/rules"
 )
 
 type SegmentRepo interface {
 	NewSegment(seg entity.Segment) error
 	NewSegmentWithAutoAssign(seg entity.Segment, percentAssigned int) ([]int, error)
-	DeleteSegment(slug string) error
+	CloneSegment(slug string, clone entity.SegmentClone) ([]int, error)
+	NewSegmentFromExpression(seg entity.Segment, expr entity.SegmentExpression) ([]int, error)
+	ArchiveSegment(slug string) error
+	RestoreSegment(slug string) error
+	PurgeSegment(slug string) error
+	AutoAssignSegment(slug string, percent int, expiresAt *time.Time) ([]int, error)
+	SetRollout(slug string, percent int) (entity.RolloutChange, error)
+	SetRolloutSchedule(slug string, steps []entity.RolloutStep) error
+	RolloutSchedule(slug string) ([]entity.RolloutStep, error)
+	ApplyNextRolloutStep(skipped []int) (entity.RolloutStep, bool, error)
+	BackfillRollouts() (int, error)
+	SetHoldout(h entity.Holdout) error
+	Holdout() (entity.Holdout, error)
+	Segments(filter entity.SegmentFilter) ([]entity.SegmentInfo, int, error)
+	Segment(slug string) (entity.SegmentInfo, error)
+	UpdateSegment(slug string, upd entity.SegmentUpdate) (entity.SegmentInfo, error)
+	SegmentChanges(slug string) ([]entity.SegmentChange, error)
 }
 
+const (
+	defaultSegmentsLimit = 20
+	maxSegmentsLimit     = 100
+
+	defaultHoldoutSalt = "holdout"
+
+	maxExpressionDepth    = 5
+	maxExpressionSegments = 20
+)
+
 type SegmentUsecase struct {
 	r SegmentRepo
 }
@@ -23,6 +52,24 @@ func NewSegmentUsecase(r SegmentRepo) *SegmentUsecase {
 func (uc *SegmentUsecase) NewSegment(seg entity.Segment) error {
 	op := "usecase.segment.New"
 
+	if seg.Rule != "" {
+		if _, err := rules.Parse(seg.Rule); err != nil {
+			return fmt.Errorf("%s: %w: %s", op, entity.ErrInvalidRule, err)
+		}
+	}
+	if !validWindow(seg.StartsAt, seg.EndsAt) {
+		return fmt.Errorf("%s: %w", op, entity.ErrInvalidSegmentWindow)
+	}
+	if !validMaxMembers(seg.MaxMembers) {
+		return fmt.Errorf("%s: %w", op, entity.ErrInvalidMaxMembers)
+	}
+	if seg.RemovalPolicy == "" {
+		seg.RemovalPolicy = entity.RemovalPolicyBlock
+	}
+	if requiresItself(seg.Slug, seg.Prerequisites) {
+		return fmt.Errorf("%s: %w", op, entity.ErrPrerequisiteCycle)
+	}
+
 	if err := uc.r.NewSegment(seg); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -30,10 +77,21 @@ func (uc *SegmentUsecase) NewSegment(seg entity.Segment) error {
 	return nil
 }
 
-// Creates a segment and returns the user IDs assigned to it
+// Creates a segment and returns the user IDs assigned to it.
+// The segment slug is used as a salt unless another one is given
 func (uc *SegmentUsecase) NewSegmentWithAutoAssign(seg entity.Segment, percentAssigned int) ([]int, error) {
 	op := "usecase.segment.NewWithAutoAssign"
 
+	if seg.AssignMode == "" {
+		seg.AssignMode = entity.AssignModeRandom
+	}
+	if seg.Salt == "" {
+		seg.Salt = seg.Slug
+	}
+	if !validMaxMembers(seg.MaxMembers) {
+		return nil, fmt.Errorf("%s: %w", op, entity.ErrInvalidMaxMembers)
+	}
+
 	ids, err := uc.r.NewSegmentWithAutoAssign(seg, percentAssigned)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
@@ -42,12 +100,323 @@ func (uc *SegmentUsecase) NewSegmentWithAutoAssign(seg entity.Segment, percentAs
 	return ids, nil
 }
 
-func (uc *SegmentUsecase) DeleteSegment(slug string) error {
-	op := "usecase.segment.Delete"
+// Copies the segment under a new slug and returns the user IDs whose memberships were copied
+func (uc *SegmentUsecase) CloneSegment(slug string, clone entity.SegmentClone) ([]int, error) {
+	op := "usecase.segment.Clone"
+
+	ids, err := uc.r.CloneSegment(slug, clone)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+// Creates a segment from a union, intersection or difference of other segments
+// and returns the user IDs assigned to it
+func (uc *SegmentUsecase) NewSegmentFromExpression(seg entity.Segment, expr entity.SegmentExpression) ([]int, error) {
+	op := "usecase.segment.NewFromExpression"
+
+	segments := 0
+	if !validExpression(expr, 1, &segments) {
+		return nil, fmt.Errorf("%s: %w", op, entity.ErrInvalidExpression)
+	}
+
+	ids, err := uc.r.NewSegmentFromExpression(seg, expr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+// Archives the segment: it is no longer returned to users and rejects new assignments,
+// but its memberships and history are kept until it is restored or purged
+func (uc *SegmentUsecase) ArchiveSegment(slug string) error {
+	op := "usecase.segment.Archive"
+
+	if err := uc.r.ArchiveSegment(slug); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (uc *SegmentUsecase) RestoreSegment(slug string) error {
+	op := "usecase.segment.Restore"
 
-	if err := uc.r.DeleteSegment(slug); err != nil {
+	if err := uc.r.RestoreSegment(slug); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
+
+// Irreversibly deletes the archived segment with all its memberships
+func (uc *SegmentUsecase) PurgeSegment(slug string) error {
+	op := "usecase.segment.Purge"
+
+	if err := uc.r.PurgeSegment(slug); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Adds the percent of users that are not in the existing segment yet and returns them.
+// Unlike the rollout it is a one-off assignment: users registered later are not enrolled
+func (uc *SegmentUsecase) AutoAssignSegment(slug string, percent int, expiresAt *time.Time) ([]int, error) {
+	op := "usecase.segment.AutoAssign"
+
+	ids, err := uc.r.AutoAssignSegment(slug, percent, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+// Raises or lowers the rollout percent of the segment. Increasing keeps the members and adds new users,
+// decreasing removes the most recently added members first (by bucket in hash mode)
+func (uc *SegmentUsecase) SetRollout(slug string, percent int) (entity.RolloutChange, error) {
+	op := "usecase.segment.SetRollout"
+
+	change, err := uc.r.SetRollout(slug, percent)
+	if err != nil {
+		return entity.RolloutChange{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return change, nil
+}
+
+// Replaces the pending steps of the segment rollout schedule and returns the whole schedule
+func (uc *SegmentUsecase) SetRolloutSchedule(slug string, steps []entity.RolloutStep) ([]entity.RolloutStep, error) {
+	op := "usecase.segment.SetRolloutSchedule"
+
+	steps = append([]entity.RolloutStep(nil), steps...)
+	sort.Slice(steps, func(i, j int) bool {
+		return steps[i].ApplyAt.Before(steps[j].ApplyAt)
+	})
+	for i, step := range steps {
+		if step.Percent < 0 || step.Percent > 100 {
+			return nil, fmt.Errorf("%s: %w", op, entity.ErrInvalidRolloutSchedule)
+		}
+		if i > 0 && step.ApplyAt.Equal(steps[i-1].ApplyAt) {
+			return nil, fmt.Errorf("%s: %w", op, entity.ErrInvalidRolloutSchedule)
+		}
+	}
+
+	if err := uc.r.SetRolloutSchedule(slug, steps); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	schedule, err := uc.r.RolloutSchedule(slug)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return schedule, nil
+}
+
+func (uc *SegmentUsecase) RolloutSchedule(slug string) ([]entity.RolloutStep, error) {
+	op := "usecase.segment.RolloutSchedule"
+
+	if _, err := uc.r.Segment(slug); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	schedule, err := uc.r.RolloutSchedule(slug)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return schedule, nil
+}
+
+// Applies all due rollout steps one by one and returns the attempted steps.
+// A step is attempted once per run, a failed one is returned without AppliedAt and is retried by the next run
+func (uc *SegmentUsecase) ApplyDueRolloutSteps() ([]entity.RolloutStep, error) {
+	op := "usecase.segment.ApplyDueRolloutSteps"
+
+	var attempted []entity.RolloutStep
+	skipped := []int{}
+	for {
+		step, ok, err := uc.r.ApplyNextRolloutStep(skipped)
+		if err != nil {
+			return attempted, fmt.Errorf("%s: %w", op, err)
+		}
+		if !ok {
+			return attempted, nil
+		}
+		attempted = append(attempted, step)
+		skipped = append(skipped, step.ID)
+	}
+}
+
+// Enrolls users into percentage segments and returns the number of added memberships
+func (uc *SegmentUsecase) BackfillRollouts() (int, error) {
+	op := "usecase.segment.BackfillRollouts"
+
+	added, err := uc.r.BackfillRollouts()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return added, nil
+}
+
+// Replaces the global holdout. Changing the salt reshuffles the holdout users,
+// existing memberships are kept
+func (uc *SegmentUsecase) SetHoldout(h entity.Holdout) (entity.Holdout, error) {
+	op := "usecase.segment.SetHoldout"
+
+	if h.Percent < 0 || h.Percent > 100 {
+		return entity.Holdout{}, fmt.Errorf("%s: %w", op, entity.ErrInvalidHoldout)
+	}
+	if h.Salt == "" {
+		h.Salt = defaultHoldoutSalt
+	}
+
+	if err := uc.r.SetHoldout(h); err != nil {
+		return entity.Holdout{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return h, nil
+}
+
+func (uc *SegmentUsecase) Holdout() (entity.Holdout, error) {
+	op := "usecase.segment.Holdout"
+
+	h, err := uc.r.Holdout()
+	if err != nil {
+		return entity.Holdout{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return h, nil
+}
+
+// Returns a page of segments matching the filter and the total number of matching segments
+func (uc *SegmentUsecase) Segments(filter entity.SegmentFilter) ([]entity.SegmentInfo, int, error) {
+	op := "usecase.segment.Segments"
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultSegmentsLimit
+	}
+	if filter.Limit > maxSegmentsLimit {
+		filter.Limit = maxSegmentsLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	if filter.Sort == "" {
+		filter.Sort = entity.SegmentSortSlug
+	}
+
+	segments, total, err := uc.r.Segments(filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return segments, total, nil
+}
+
+func (uc *SegmentUsecase) Segment(slug string) (entity.SegmentInfo, error) {
+	op := "usecase.segment.Segment"
+
+	seg, err := uc.r.Segment(slug)
+	if err != nil {
+		return entity.SegmentInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return seg, nil
+}
+
+// Edits segment metadata and returns the updated segment
+func (uc *SegmentUsecase) UpdateSegment(slug string, upd entity.SegmentUpdate) (entity.SegmentInfo, error) {
+	op := "usecase.segment.Update"
+
+	if !validWindow(upd.StartsAt, upd.EndsAt) {
+		return entity.SegmentInfo{}, fmt.Errorf("%s: %w", op, entity.ErrInvalidSegmentWindow)
+	}
+	if upd.ClearWindow && (upd.StartsAt != nil || upd.EndsAt != nil) {
+		return entity.SegmentInfo{}, fmt.Errorf("%s: %w", op, entity.ErrInvalidSegmentWindow)
+	}
+	if !validMaxMembers(upd.MaxMembers) {
+		return entity.SegmentInfo{}, fmt.Errorf("%s: %w", op, entity.ErrInvalidMaxMembers)
+	}
+	if upd.ClearMaxMembers && upd.MaxMembers != nil {
+		return entity.SegmentInfo{}, fmt.Errorf("%s: %w", op, entity.ErrInvalidMaxMembers)
+	}
+	if requiresItself(slug, upd.Prerequisites) {
+		return entity.SegmentInfo{}, fmt.Errorf("%s: %w", op, entity.ErrPrerequisiteCycle)
+	}
+
+	seg, err := uc.r.UpdateSegment(slug, upd)
+	if err != nil {
+		return entity.SegmentInfo{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return seg, nil
+}
+
+// Returns the audit trail of the segment, oldest changes first
+func (uc *SegmentUsecase) SegmentChanges(slug string) ([]entity.SegmentChange, error) {
+	op := "usecase.segment.SegmentChanges"
+
+	if _, err := uc.r.Segment(slug); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	changes, err := uc.r.SegmentChanges(slug)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return changes, nil
+}
+
+// Longer cycles are checked by the repository, which sees the prerequisites of the other segments
+func requiresItself(slug string, prerequisites []string) bool {
+	for _, prerequisite := range prerequisites {
+		if prerequisite == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// Open bounds are always valid, the stored bound is checked by the database
+func validWindow(startsAt, endsAt *time.Time) bool {
+	return startsAt == nil || endsAt == nil || startsAt.Before(*endsAt)
+}
+
+// A leaf has only a slug, an operation has at least two arguments.
+// The depth and the number of leaves are limited to keep the generated query small
+func validExpression(expr entity.SegmentExpression, depth int, segments *int) bool {
+	if depth > maxExpressionDepth {
+		return false
+	}
+
+	switch expr.Op {
+	case "":
+		*segments++
+		return expr.Slug != "" && len(expr.Args) == 0 && *segments <= maxExpressionSegments
+	case entity.SetUnion, entity.SetIntersection, entity.SetDifference:
+		if expr.Slug != "" || len(expr.Args) < 2 {
+			return false
+		}
+		for _, arg := range expr.Args {
+			if !validExpression(arg, depth+1, segments) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// Lowering the capacity below the current number of members keeps them, but blocks new assignments
+func validMaxMembers(maxMembers *int) bool {
+	return maxMembers == nil || *maxMembers > 0
+}
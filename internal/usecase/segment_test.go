@@ -2,9 +2,11 @@ package usecase
 
 import (
 	"testing"
+	"time"
 
 	"experiment.io/internal/entity"
 	"experiment.io/internal/mocks"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -12,6 +14,10 @@ func TestNewSegment(t *testing.T) {
 	r := new(mocks.SegmentRepo)
 	uc := NewSegmentUsecase(r)
 
+	startsAt := time.Date(2023, time.September, 1, 0, 0, 0, 0, time.UTC)
+	endsAt := startsAt.Add(7 * 24 * time.Hour)
+	maxMembers, zeroMembers := 1000, 0
+
 	testCases := []struct {
 		name        string
 		segment     entity.Segment
@@ -34,11 +40,99 @@ func TestNewSegment(t *testing.T) {
 			repoErr:     entity.ErrSegmentAlreadyExist,
 			expectedErr: entity.ErrSegmentAlreadyExist,
 		},
+		{
+			name: "Valid targeting rule",
+			segment: entity.Segment{
+				Slug: "slug",
+				Rule: `country in ["RU", "KZ"] and platform == "ios"`,
+			},
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name: "Invalid targeting rule",
+			segment: entity.Segment{
+				Slug: "slug",
+				Rule: `country = "RU"`,
+			},
+			repoErr:     nil,
+			expectedErr: entity.ErrInvalidRule,
+		},
+		{
+			name: "Activation window",
+			segment: entity.Segment{
+				Slug:     "slug",
+				StartsAt: &startsAt,
+				EndsAt:   &endsAt,
+			},
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name: "Activation window ends before it starts",
+			segment: entity.Segment{
+				Slug:     "slug",
+				StartsAt: &endsAt,
+				EndsAt:   &startsAt,
+			},
+			repoErr:     nil,
+			expectedErr: entity.ErrInvalidSegmentWindow,
+		},
+		{
+			name: "Prerequisites",
+			segment: entity.Segment{
+				Slug:          "CHECKOUT_V2_PROMO",
+				Prerequisites: []string{"CHECKOUT_V2"},
+				RemovalPolicy: entity.RemovalPolicyCascade,
+			},
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name: "Prerequisite of itself",
+			segment: entity.Segment{
+				Slug:          "slug",
+				Prerequisites: []string{"slug"},
+			},
+			repoErr:     nil,
+			expectedErr: entity.ErrPrerequisiteCycle,
+		},
+		{
+			name: "Prerequisites cycle",
+			segment: entity.Segment{
+				Slug:          "A",
+				Prerequisites: []string{"B"},
+			},
+			repoErr:     entity.ErrPrerequisiteCycle,
+			expectedErr: entity.ErrPrerequisiteCycle,
+		},
+		{
+			name: "Limited segment",
+			segment: entity.Segment{
+				Slug:       "slug",
+				MaxMembers: &maxMembers,
+			},
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name: "Zero max members",
+			segment: entity.Segment{
+				Slug:       "slug",
+				MaxMembers: &zeroMembers,
+			},
+			repoErr:     nil,
+			expectedErr: entity.ErrInvalidMaxMembers,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockCall := r.On("NewSegment", tc.segment).Return(tc.expectedErr)
+			repoSegment := tc.segment
+			if repoSegment.RemovalPolicy == "" {
+				repoSegment.RemovalPolicy = entity.RemovalPolicyBlock
+			}
+			mockCall := r.On("NewSegment", repoSegment).Return(tc.repoErr)
 
 			err := uc.NewSegment(tc.segment)
 			require.ErrorIs(t, err, tc.expectedErr)
@@ -48,7 +142,7 @@ func TestNewSegment(t *testing.T) {
 	}
 }
 
-func TestDeleteSegment(t *testing.T) {
+func TestArchiveSegment(t *testing.T) {
 	r := new(mocks.SegmentRepo)
 	uc := NewSegmentUsecase(r)
 
@@ -59,13 +153,13 @@ func TestDeleteSegment(t *testing.T) {
 		expectedErr error
 	}{
 		{
-			name:        "Uniq slug",
+			name:        "Existent slug",
 			slug:        "slug",
 			repoErr:     nil,
 			expectedErr: nil,
 		},
 		{
-			name:        "Duplicate slug",
+			name:        "Non-existent slug",
 			slug:        "slug",
 			repoErr:     entity.ErrSegmentNotFound,
 			expectedErr: entity.ErrSegmentNotFound,
@@ -74,8 +168,8 @@ func TestDeleteSegment(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockCall := r.On("DeleteSegment", tc.slug).Return(tc.repoErr)
-			err := uc.DeleteSegment(tc.slug)
+			mockCall := r.On("ArchiveSegment", tc.slug).Return(tc.repoErr)
+			err := uc.ArchiveSegment(tc.slug)
 
 			require.ErrorIs(t, err, tc.expectedErr)
 
@@ -84,43 +178,67 @@ func TestDeleteSegment(t *testing.T) {
 	}
 }
 
-
 func TestNewSegmentWithAutoAssign(t *testing.T) {
 	r := new(mocks.SegmentRepo)
 	uc := NewSegmentUsecase(r)
 
+	expiresAt := time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC)
+
 	testCases := []struct {
-		name          string
-		segment       entity.Segment
+		name            string
+		segment         entity.Segment
+		repoSegment     entity.Segment
 		percentAssigned int
-		repoIDs       []int
-		repoErr       error
-		expectedIDs   []int
-		expectedErr   error
+		repoIDs         []int
+		repoErr         error
+		expectedIDs     []int
+		expectedErr     error
 	}{
 		{
-			name:           "Success",
-			segment:        entity.Segment{},
+			name:            "Success",
+			segment:         entity.Segment{Slug: "slug"},
+			repoSegment:     entity.Segment{Slug: "slug", Salt: "slug", AssignMode: entity.AssignModeRandom},
 			percentAssigned: 50,
-			repoIDs:        []int{1, 2, 3},
-			repoErr:        nil,
-			expectedIDs:    []int{1, 2, 3},
-			expectedErr:    nil,
+			repoIDs:         []int{1, 2, 3},
+			repoErr:         nil,
+			expectedIDs:     []int{1, 2, 3},
+			expectedErr:     nil,
+		},
+		{
+			name:            "Hash mode with own salt",
+			segment:         entity.Segment{Slug: "slug", Salt: "salt", AssignMode: entity.AssignModeHash},
+			repoSegment:     entity.Segment{Slug: "slug", Salt: "salt", AssignMode: entity.AssignModeHash},
+			percentAssigned: 50,
+			repoIDs:         []int{2},
+			repoErr:         nil,
+			expectedIDs:     []int{2},
+			expectedErr:     nil,
+		},
+		{
+			name:            "Expiring memberships",
+			segment:         entity.Segment{Slug: "slug", RolloutExpiresAt: &expiresAt},
+			repoSegment:     entity.Segment{Slug: "slug", Salt: "slug", AssignMode: entity.AssignModeRandom, RolloutExpiresAt: &expiresAt},
+			percentAssigned: 50,
+			repoIDs:         []int{1},
+			repoErr:         nil,
+			expectedIDs:     []int{1},
+			expectedErr:     nil,
 		},
 		{
-			name:           "Repository Error",
-			segment:        entity.Segment{},
+			name:            "Repository Error",
+			segment:         entity.Segment{Slug: "slug"},
+			repoSegment:     entity.Segment{Slug: "slug", Salt: "slug", AssignMode: entity.AssignModeRandom},
 			percentAssigned: 75,
-			repoIDs:        nil,
-			repoErr:        entity.ErrInternalServer,
-			expectedIDs:    nil,
-			expectedErr:    entity.ErrInternalServer,
+			repoIDs:         nil,
+			repoErr:         entity.ErrInternalServer,
+			expectedIDs:     nil,
+			expectedErr:     entity.ErrInternalServer,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockCall := r.On("NewSegmentWithAutoAssign", tc.segment, tc.percentAssigned).Return(tc.repoIDs, tc.repoErr)
+			mockCall := r.On("NewSegmentWithAutoAssign", tc.repoSegment, tc.percentAssigned).Return(tc.repoIDs, tc.repoErr)
 			ids, err := uc.NewSegmentWithAutoAssign(tc.segment, tc.percentAssigned)
 
 			require.Equal(t, tc.expectedIDs, ids)
@@ -129,4 +247,853 @@ func TestNewSegmentWithAutoAssign(t *testing.T) {
 			mockCall.Unset()
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestCloneSegment(t *testing.T) {
+	r := new(mocks.SegmentRepo)
+	uc := NewSegmentUsecase(r)
+
+	testCases := []struct {
+		name        string
+		slug        string
+		clone       entity.SegmentClone
+		repoIDs     []int
+		repoErr     error
+		expectedIDs []int
+		expectedErr error
+	}{
+		{
+			name:        "Copy members",
+			slug:        "CHECKOUT_V2",
+			clone:       entity.SegmentClone{Slug: "CHECKOUT_V3", CopyMembers: true},
+			repoIDs:     []int{1, 2},
+			repoErr:     nil,
+			expectedIDs: []int{1, 2},
+			expectedErr: nil,
+		},
+		{
+			name:        "Metadata only",
+			slug:        "CHECKOUT_V2",
+			clone:       entity.SegmentClone{Slug: "CHECKOUT_V3"},
+			repoIDs:     []int{},
+			repoErr:     nil,
+			expectedIDs: []int{},
+			expectedErr: nil,
+		},
+		{
+			name:        "Non-existent source",
+			slug:        "CHECKOUT_V2",
+			clone:       entity.SegmentClone{Slug: "CHECKOUT_V3"},
+			repoIDs:     nil,
+			repoErr:     entity.ErrSegmentNotFound,
+			expectedIDs: nil,
+			expectedErr: entity.ErrSegmentNotFound,
+		},
+		{
+			name:        "Slug is taken",
+			slug:        "CHECKOUT_V2",
+			clone:       entity.SegmentClone{Slug: "CHECKOUT_V2"},
+			repoIDs:     nil,
+			repoErr:     entity.ErrSegmentAlreadyExist,
+			expectedIDs: nil,
+			expectedErr: entity.ErrSegmentAlreadyExist,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("CloneSegment", tc.slug, tc.clone).Return(tc.repoIDs, tc.repoErr)
+			ids, err := uc.CloneSegment(tc.slug, tc.clone)
+
+			require.Equal(t, tc.expectedIDs, ids)
+			require.ErrorIs(t, err, tc.expectedErr)
+
+			mockCall.Unset()
+		})
+	}
+}
+
+func TestNewSegmentFromExpression(t *testing.T) {
+	r := new(mocks.SegmentRepo)
+	uc := NewSegmentUsecase(r)
+
+	leaf := func(slug string) entity.SegmentExpression {
+		return entity.SegmentExpression{Slug: slug}
+	}
+	deep := leaf("A")
+	for i := 0; i < maxExpressionDepth; i++ {
+		deep = entity.SegmentExpression{Op: entity.SetUnion, Args: []entity.SegmentExpression{deep, leaf("B")}}
+	}
+
+	testCases := []struct {
+		name        string
+		expr        entity.SegmentExpression
+		repoIDs     []int
+		repoErr     error
+		expectedIDs []int
+		expectedErr error
+	}{
+		{
+			name: "Difference",
+			expr: entity.SegmentExpression{
+				Op:   entity.SetDifference,
+				Args: []entity.SegmentExpression{leaf("A"), leaf("B")},
+			},
+			repoIDs:     []int{1, 3},
+			repoErr:     nil,
+			expectedIDs: []int{1, 3},
+			expectedErr: nil,
+		},
+		{
+			name: "Nested intersection",
+			expr: entity.SegmentExpression{
+				Op: entity.SetIntersection,
+				Args: []entity.SegmentExpression{
+					leaf("A"),
+					{Op: entity.SetUnion, Args: []entity.SegmentExpression{leaf("B"), leaf("C")}},
+				},
+			},
+			repoIDs:     []int{2},
+			repoErr:     nil,
+			expectedIDs: []int{2},
+			expectedErr: nil,
+		},
+		{
+			name:        "Single segment",
+			expr:        leaf("A"),
+			repoIDs:     []int{1, 2},
+			repoErr:     nil,
+			expectedIDs: []int{1, 2},
+			expectedErr: nil,
+		},
+		{
+			name: "Unknown operation",
+			expr: entity.SegmentExpression{
+				Op:   "xor",
+				Args: []entity.SegmentExpression{leaf("A"), leaf("B")},
+			},
+			expectedErr: entity.ErrInvalidExpression,
+		},
+		{
+			name: "Operation with one argument",
+			expr: entity.SegmentExpression{
+				Op:   entity.SetUnion,
+				Args: []entity.SegmentExpression{leaf("A")},
+			},
+			expectedErr: entity.ErrInvalidExpression,
+		},
+		{
+			name:        "Empty expression",
+			expr:        entity.SegmentExpression{},
+			expectedErr: entity.ErrInvalidExpression,
+		},
+		{
+			name:        "Too deep expression",
+			expr:        deep,
+			expectedErr: entity.ErrInvalidExpression,
+		},
+		{
+			name: "Non-existent segment",
+			expr: entity.SegmentExpression{
+				Op:   entity.SetUnion,
+				Args: []entity.SegmentExpression{leaf("A"), leaf("Z")},
+			},
+			repoIDs:     nil,
+			repoErr:     entity.ErrSegmentNotFound,
+			expectedIDs: nil,
+			expectedErr: entity.ErrSegmentNotFound,
+		},
+	}
+
+	seg := entity.Segment{Slug: "slug"}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("NewSegmentFromExpression", seg, tc.expr).Return(tc.repoIDs, tc.repoErr)
+			ids, err := uc.NewSegmentFromExpression(seg, tc.expr)
+
+			require.Equal(t, tc.expectedIDs, ids)
+			require.ErrorIs(t, err, tc.expectedErr)
+
+			mockCall.Unset()
+		})
+	}
+}
+
+func TestAutoAssignSegment(t *testing.T) {
+	r := new(mocks.SegmentRepo)
+	uc := NewSegmentUsecase(r)
+
+	expiresAt := time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name        string
+		slug        string
+		percent     int
+		expiresAt   *time.Time
+		repoIDs     []int
+		repoErr     error
+		expectedIDs []int
+		expectedErr error
+	}{
+		{
+			name:        "Success",
+			slug:        "AVITO_DISCOUNT_30",
+			percent:     50,
+			expiresAt:   nil,
+			repoIDs:     []int{4, 7},
+			repoErr:     nil,
+			expectedIDs: []int{4, 7},
+			expectedErr: nil,
+		},
+		{
+			name:        "With expiration",
+			slug:        "AVITO_DISCOUNT_30",
+			percent:     50,
+			expiresAt:   &expiresAt,
+			repoIDs:     []int{4},
+			repoErr:     nil,
+			expectedIDs: []int{4},
+			expectedErr: nil,
+		},
+		{
+			name:        "Non-existent slug",
+			slug:        "AVITO_DISCOUNT_30",
+			percent:     50,
+			repoIDs:     nil,
+			repoErr:     entity.ErrSegmentNotFound,
+			expectedIDs: nil,
+			expectedErr: entity.ErrSegmentNotFound,
+		},
+		{
+			name:        "Archived segment",
+			slug:        "AVITO_DISCOUNT_30",
+			percent:     50,
+			repoIDs:     nil,
+			repoErr:     entity.ErrSegmentArchived,
+			expectedIDs: nil,
+			expectedErr: entity.ErrSegmentArchived,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("AutoAssignSegment", tc.slug, tc.percent, tc.expiresAt).Return(tc.repoIDs, tc.repoErr)
+			ids, err := uc.AutoAssignSegment(tc.slug, tc.percent, tc.expiresAt)
+
+			require.Equal(t, tc.expectedIDs, ids)
+			require.ErrorIs(t, err, tc.expectedErr)
+
+			mockCall.Unset()
+		})
+	}
+}
+
+func TestBackfillRollouts(t *testing.T) {
+	r := new(mocks.SegmentRepo)
+	uc := NewSegmentUsecase(r)
+
+	testCases := []struct {
+		name          string
+		repoAdded     int
+		repoErr       error
+		expectedAdded int
+		expectedErr   error
+	}{
+		{
+			name:          "Success",
+			repoAdded:     5,
+			repoErr:       nil,
+			expectedAdded: 5,
+			expectedErr:   nil,
+		},
+		{
+			name:          "Repository Error",
+			repoAdded:     0,
+			repoErr:       entity.ErrInternalServer,
+			expectedAdded: 0,
+			expectedErr:   entity.ErrInternalServer,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("BackfillRollouts").Return(tc.repoAdded, tc.repoErr)
+			added, err := uc.BackfillRollouts()
+
+			require.Equal(t, tc.expectedAdded, added)
+			require.ErrorIs(t, err, tc.expectedErr)
+
+			mockCall.Unset()
+		})
+	}
+}
+
+func TestSegments(t *testing.T) {
+	r := new(mocks.SegmentRepo)
+	uc := NewSegmentUsecase(r)
+
+	testCases := []struct {
+		name        string
+		filter      entity.SegmentFilter
+		repoFilter  entity.SegmentFilter
+		repoErr     error
+		expectedErr error
+	}{
+		{
+			name:        "Default filter",
+			filter:      entity.SegmentFilter{},
+			repoFilter:  entity.SegmentFilter{Sort: entity.SegmentSortSlug, Limit: 20},
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name:        "Limit is too big",
+			filter:      entity.SegmentFilter{Prefix: "AVITO", Sort: entity.SegmentSortMembers, Desc: true, Limit: 1000, Offset: 10},
+			repoFilter:  entity.SegmentFilter{Prefix: "AVITO", Sort: entity.SegmentSortMembers, Desc: true, Limit: 100, Offset: 10},
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name:        "Repository Error",
+			filter:      entity.SegmentFilter{Limit: 10},
+			repoFilter:  entity.SegmentFilter{Sort: entity.SegmentSortSlug, Limit: 10},
+			repoErr:     entity.ErrInternalServer,
+			expectedErr: entity.ErrInternalServer,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("Segments", tc.repoFilter).Return([]entity.SegmentInfo{}, 0, tc.repoErr)
+			_, _, err := uc.Segments(tc.filter)
+
+			require.ErrorIs(t, err, tc.expectedErr)
+
+			mockCall.Unset()
+		})
+	}
+}
+
+func TestSegment(t *testing.T) {
+	r := new(mocks.SegmentRepo)
+	uc := NewSegmentUsecase(r)
+
+	testCases := []struct {
+		name        string
+		slug        string
+		repoSegment entity.SegmentInfo
+		repoErr     error
+		expectedErr error
+	}{
+		{
+			name:        "Success",
+			slug:        "slug",
+			repoSegment: entity.SegmentInfo{Segment: entity.Segment{Slug: "slug"}, Members: 3},
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name:        "Non-existent slug",
+			slug:        "slug",
+			repoSegment: entity.SegmentInfo{},
+			repoErr:     entity.ErrSegmentNotFound,
+			expectedErr: entity.ErrSegmentNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("Segment", tc.slug).Return(tc.repoSegment, tc.repoErr)
+			seg, err := uc.Segment(tc.slug)
+
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.repoSegment, seg)
+
+			mockCall.Unset()
+		})
+	}
+}
+
+func TestUpdateSegment(t *testing.T) {
+	r := new(mocks.SegmentRepo)
+	uc := NewSegmentUsecase(r)
+
+	owner := "pricing"
+	startsAt := time.Date(2023, time.September, 1, 0, 0, 0, 0, time.UTC)
+	endsAt := startsAt.Add(7 * 24 * time.Hour)
+	maxMembers := 100
+	testCases := []struct {
+		name        string
+		slug        string
+		upd         entity.SegmentUpdate
+		repoErr     error
+		expectedErr error
+	}{
+		{
+			name:        "Success",
+			slug:        "slug",
+			upd:         entity.SegmentUpdate{Owner: &owner, Tags: []string{"promo"}},
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name:        "Non-existent slug",
+			slug:        "slug",
+			upd:         entity.SegmentUpdate{Owner: &owner},
+			repoErr:     entity.ErrSegmentNotFound,
+			expectedErr: entity.ErrSegmentNotFound,
+		},
+		{
+			name:        "Activation window ends before it starts",
+			slug:        "slug",
+			upd:         entity.SegmentUpdate{StartsAt: &endsAt, EndsAt: &startsAt},
+			repoErr:     nil,
+			expectedErr: entity.ErrInvalidSegmentWindow,
+		},
+		{
+			name:        "Activation window ends before the stored start",
+			slug:        "slug",
+			upd:         entity.SegmentUpdate{EndsAt: &startsAt},
+			repoErr:     entity.ErrInvalidSegmentWindow,
+			expectedErr: entity.ErrInvalidSegmentWindow,
+		},
+		{
+			name:        "Clear activation window",
+			slug:        "slug",
+			upd:         entity.SegmentUpdate{ClearWindow: true},
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name:        "Clear activation window and set its end",
+			slug:        "slug",
+			upd:         entity.SegmentUpdate{EndsAt: &endsAt, ClearWindow: true},
+			repoErr:     nil,
+			expectedErr: entity.ErrInvalidSegmentWindow,
+		},
+		{
+			name:        "Make the segment unlimited",
+			slug:        "slug",
+			upd:         entity.SegmentUpdate{ClearMaxMembers: true},
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name:        "Make the segment unlimited and set its limit",
+			slug:        "slug",
+			upd:         entity.SegmentUpdate{MaxMembers: &maxMembers, ClearMaxMembers: true},
+			repoErr:     nil,
+			expectedErr: entity.ErrInvalidMaxMembers,
+		},
+		{
+			name:        "Remove prerequisites",
+			slug:        "slug",
+			upd:         entity.SegmentUpdate{Prerequisites: []string{}},
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name:        "Prerequisites cycle",
+			slug:        "CHECKOUT_V2",
+			upd:         entity.SegmentUpdate{Prerequisites: []string{"CHECKOUT_V2_PROMO"}},
+			repoErr:     entity.ErrPrerequisiteCycle,
+			expectedErr: entity.ErrPrerequisiteCycle,
+		},
+		{
+			name:        "Prerequisite of itself",
+			slug:        "CHECKOUT_V2",
+			upd:         entity.SegmentUpdate{Prerequisites: []string{"CHECKOUT_V2"}},
+			repoErr:     nil,
+			expectedErr: entity.ErrPrerequisiteCycle,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("UpdateSegment", tc.slug, tc.upd).Return(entity.SegmentInfo{}, tc.repoErr)
+			_, err := uc.UpdateSegment(tc.slug, tc.upd)
+
+			require.ErrorIs(t, err, tc.expectedErr)
+
+			mockCall.Unset()
+		})
+	}
+}
+
+func TestSegmentChanges(t *testing.T) {
+	r := new(mocks.SegmentRepo)
+	uc := NewSegmentUsecase(r)
+
+	testCases := []struct {
+		name           string
+		slug           string
+		repoSegmentErr error
+		repoErr        error
+		expectedErr    error
+	}{
+		{
+			name:           "Success",
+			slug:           "slug",
+			repoSegmentErr: nil,
+			repoErr:        nil,
+			expectedErr:    nil,
+		},
+		{
+			name:           "Non-existent slug",
+			slug:           "slug",
+			repoSegmentErr: entity.ErrSegmentNotFound,
+			repoErr:        nil,
+			expectedErr:    entity.ErrSegmentNotFound,
+		},
+		{
+			name:           "Error fetching changes",
+			slug:           "slug",
+			repoSegmentErr: nil,
+			repoErr:        entity.ErrInternalServer,
+			expectedErr:    entity.ErrInternalServer,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSegmentCall := r.On("Segment", tc.slug).Return(entity.SegmentInfo{}, tc.repoSegmentErr)
+			mockCall := r.On("SegmentChanges", tc.slug).Return([]entity.SegmentChange{}, tc.repoErr)
+			_, err := uc.SegmentChanges(tc.slug)
+
+			require.ErrorIs(t, err, tc.expectedErr)
+
+			mockSegmentCall.Unset()
+			mockCall.Unset()
+		})
+	}
+}
+
+func TestRestoreSegment(t *testing.T) {
+	r := new(mocks.SegmentRepo)
+	uc := NewSegmentUsecase(r)
+
+	testCases := []struct {
+		name        string
+		slug        string
+		repoErr     error
+		expectedErr error
+	}{
+		{
+			name:        "Existent slug",
+			slug:        "slug",
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name:        "Non-existent slug",
+			slug:        "slug",
+			repoErr:     entity.ErrSegmentNotFound,
+			expectedErr: entity.ErrSegmentNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("RestoreSegment", tc.slug).Return(tc.repoErr)
+			err := uc.RestoreSegment(tc.slug)
+
+			require.ErrorIs(t, err, tc.expectedErr)
+
+			mockCall.Unset()
+		})
+	}
+}
+
+func TestPurgeSegment(t *testing.T) {
+	r := new(mocks.SegmentRepo)
+	uc := NewSegmentUsecase(r)
+
+	testCases := []struct {
+		name        string
+		slug        string
+		repoErr     error
+		expectedErr error
+	}{
+		{
+			name:        "Archived segment",
+			slug:        "slug",
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name:        "Active segment",
+			slug:        "slug",
+			repoErr:     entity.ErrSegmentNotArchived,
+			expectedErr: entity.ErrSegmentNotArchived,
+		},
+		{
+			name:        "Non-existent slug",
+			slug:        "slug",
+			repoErr:     entity.ErrSegmentNotFound,
+			expectedErr: entity.ErrSegmentNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("PurgeSegment", tc.slug).Return(tc.repoErr)
+			err := uc.PurgeSegment(tc.slug)
+
+			require.ErrorIs(t, err, tc.expectedErr)
+
+			mockCall.Unset()
+		})
+	}
+}
+
+func TestSetRollout(t *testing.T) {
+	r := new(mocks.SegmentRepo)
+	uc := NewSegmentUsecase(r)
+
+	testCases := []struct {
+		name           string
+		slug           string
+		percent        int
+		repoChange     entity.RolloutChange
+		repoErr        error
+		expectedChange entity.RolloutChange
+		expectedErr    error
+	}{
+		{
+			name:           "Increase",
+			slug:           "slug",
+			percent:        50,
+			repoChange:     entity.RolloutChange{Added: []int{3, 4}, Removed: []int{}},
+			repoErr:        nil,
+			expectedChange: entity.RolloutChange{Added: []int{3, 4}, Removed: []int{}},
+			expectedErr:    nil,
+		},
+		{
+			name:           "Decrease",
+			slug:           "slug",
+			percent:        5,
+			repoChange:     entity.RolloutChange{Added: []int{}, Removed: []int{4}},
+			repoErr:        nil,
+			expectedChange: entity.RolloutChange{Added: []int{}, Removed: []int{4}},
+			expectedErr:    nil,
+		},
+		{
+			name:           "Archived segment",
+			slug:           "slug",
+			percent:        20,
+			repoChange:     entity.RolloutChange{},
+			repoErr:        entity.ErrSegmentArchived,
+			expectedChange: entity.RolloutChange{},
+			expectedErr:    entity.ErrSegmentArchived,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("SetRollout", tc.slug, tc.percent).Return(tc.repoChange, tc.repoErr)
+			change, err := uc.SetRollout(tc.slug, tc.percent)
+
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expectedChange, change)
+
+			mockCall.Unset()
+		})
+	}
+}
+
+func TestSetRolloutSchedule(t *testing.T) {
+	r := new(mocks.SegmentRepo)
+	uc := NewSegmentUsecase(r)
+
+	monday := time.Date(2023, time.September, 18, 10, 0, 0, 0, time.UTC)
+	wednesday := monday.Add(2 * 24 * time.Hour)
+
+	testCases := []struct {
+		name        string
+		slug        string
+		steps       []entity.RolloutStep
+		repoSteps   []entity.RolloutStep
+		repoErr     error
+		expectedErr error
+	}{
+		{
+			name: "Steps are sorted by time",
+			slug: "slug",
+			steps: []entity.RolloutStep{
+				{Percent: 30, ApplyAt: wednesday},
+				{Percent: 10, ApplyAt: monday},
+			},
+			repoSteps: []entity.RolloutStep{
+				{Percent: 10, ApplyAt: monday},
+				{Percent: 30, ApplyAt: wednesday},
+			},
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name: "Steps at the same time",
+			slug: "slug",
+			steps: []entity.RolloutStep{
+				{Percent: 10, ApplyAt: monday},
+				{Percent: 30, ApplyAt: monday},
+			},
+			repoErr:     nil,
+			expectedErr: entity.ErrInvalidRolloutSchedule,
+		},
+		{
+			name: "Percent out of range",
+			slug: "slug",
+			steps: []entity.RolloutStep{
+				{Percent: 101, ApplyAt: monday},
+			},
+			repoErr:     nil,
+			expectedErr: entity.ErrInvalidRolloutSchedule,
+		},
+		{
+			name: "Non-existent slug",
+			slug: "slug",
+			steps: []entity.RolloutStep{
+				{Percent: 10, ApplyAt: monday},
+			},
+			repoSteps: []entity.RolloutStep{
+				{Percent: 10, ApplyAt: monday},
+			},
+			repoErr:     entity.ErrSegmentNotFound,
+			expectedErr: entity.ErrSegmentNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("SetRolloutSchedule", tc.slug, tc.repoSteps).Return(tc.repoErr)
+			mockScheduleCall := r.On("RolloutSchedule", tc.slug).Return(tc.repoSteps, nil)
+			_, err := uc.SetRolloutSchedule(tc.slug, tc.steps)
+
+			require.ErrorIs(t, err, tc.expectedErr)
+
+			mockCall.Unset()
+			mockScheduleCall.Unset()
+		})
+	}
+}
+
+func TestApplyDueRolloutSteps(t *testing.T) {
+	appliedAt := time.Date(2023, time.September, 18, 10, 0, 0, 0, time.UTC)
+	step := entity.RolloutStep{ID: 1, SegmentSlug: "slug", Percent: 30, AppliedAt: &appliedAt}
+	failedStep := entity.RolloutStep{ID: 2, SegmentSlug: "variant", Percent: 10, Attempts: 1,
+		LastError: entity.ErrNotRolloutSegment.Error()}
+
+	t.Run("Applies steps until none is due", func(t *testing.T) {
+		r := new(mocks.SegmentRepo)
+		uc := NewSegmentUsecase(r)
+		r.On("ApplyNextRolloutStep", mock.Anything).Return(step, true, nil).Twice()
+		r.On("ApplyNextRolloutStep", mock.Anything).Return(entity.RolloutStep{}, false, nil).Once()
+
+		applied, err := uc.ApplyDueRolloutSteps()
+		require.NoError(t, err)
+		require.Equal(t, []entity.RolloutStep{step, step}, applied)
+	})
+
+	t.Run("Continues after a failed step", func(t *testing.T) {
+		r := new(mocks.SegmentRepo)
+		uc := NewSegmentUsecase(r)
+		r.On("ApplyNextRolloutStep", mock.Anything).Return(failedStep, true, nil).Once()
+		r.On("ApplyNextRolloutStep", mock.Anything).Return(step, true, nil).Once()
+		r.On("ApplyNextRolloutStep", mock.Anything).Return(entity.RolloutStep{}, false, nil).Once()
+
+		attempted, err := uc.ApplyDueRolloutSteps()
+		require.NoError(t, err)
+		require.Equal(t, []entity.RolloutStep{failedStep, step}, attempted)
+	})
+
+	t.Run("Attempts an always failing step once per run", func(t *testing.T) {
+		r := new(mocks.SegmentRepo)
+		uc := NewSegmentUsecase(r)
+		calls := 0
+		r.On("ApplyNextRolloutStep", mock.Anything).Return(func(skipped []int) (entity.RolloutStep, bool, error) {
+			calls++
+			if calls > 3 {
+				return entity.RolloutStep{}, false, entity.ErrInternalServer
+			}
+			for _, id := range skipped {
+				if id == failedStep.ID {
+					return entity.RolloutStep{}, false, nil
+				}
+			}
+			return failedStep, true, nil
+		})
+
+		attempted, err := uc.ApplyDueRolloutSteps()
+		require.NoError(t, err)
+		require.Equal(t, []entity.RolloutStep{failedStep}, attempted)
+		require.Equal(t, 2, calls)
+	})
+
+	t.Run("Returns the steps applied before an error", func(t *testing.T) {
+		r := new(mocks.SegmentRepo)
+		uc := NewSegmentUsecase(r)
+		r.On("ApplyNextRolloutStep", mock.Anything).Return(step, true, nil).Once()
+		r.On("ApplyNextRolloutStep", mock.Anything).Return(entity.RolloutStep{}, false, entity.ErrInternalServer).Once()
+
+		applied, err := uc.ApplyDueRolloutSteps()
+		require.ErrorIs(t, err, entity.ErrInternalServer)
+		require.Equal(t, []entity.RolloutStep{step}, applied)
+	})
+}
+
+func TestSetHoldout(t *testing.T) {
+	r := new(mocks.SegmentRepo)
+	uc := NewSegmentUsecase(r)
+
+	testCases := []struct {
+		name            string
+		holdout         entity.Holdout
+		repoHoldout     entity.Holdout
+		repoErr         error
+		expectedHoldout entity.Holdout
+		expectedErr     error
+	}{
+		{
+			name:            "Success",
+			holdout:         entity.Holdout{Salt: "holdout-2023", Percent: 5},
+			repoHoldout:     entity.Holdout{Salt: "holdout-2023", Percent: 5},
+			repoErr:         nil,
+			expectedHoldout: entity.Holdout{Salt: "holdout-2023", Percent: 5},
+			expectedErr:     nil,
+		},
+		{
+			name:            "Default salt",
+			holdout:         entity.Holdout{Percent: 5},
+			repoHoldout:     entity.Holdout{Salt: "holdout", Percent: 5},
+			repoErr:         nil,
+			expectedHoldout: entity.Holdout{Salt: "holdout", Percent: 5},
+			expectedErr:     nil,
+		},
+		{
+			name:            "Percent out of range",
+			holdout:         entity.Holdout{Percent: 101},
+			repoErr:         nil,
+			expectedHoldout: entity.Holdout{},
+			expectedErr:     entity.ErrInvalidHoldout,
+		},
+		{
+			name:            "Repository error",
+			holdout:         entity.Holdout{Percent: 5},
+			repoHoldout:     entity.Holdout{Salt: "holdout", Percent: 5},
+			repoErr:         entity.ErrInternalServer,
+			expectedHoldout: entity.Holdout{},
+			expectedErr:     entity.ErrInternalServer,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("SetHoldout", tc.repoHoldout).Return(tc.repoErr)
+			holdout, err := uc.SetHoldout(tc.holdout)
+
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expectedHoldout, holdout)
+
+			mockCall.Unset()
+		})
+	}
+}
@@ -2,24 +2,43 @@ package usecase
 
 import (
 	"fmt"
+	"hash/fnv"
+	"time"
 
 	"experiment.io/internal/entity"
+	"experiment.io/pkg/bucket"
+	"experiment.io/pkg/logger"
+	"experiment.io/pkg/rules"
 )
 
 type UserRepo interface {
 	UserSegments(userID int) ([]entity.SlugWithExpiredDate, error)
+	UserAttributes(userID int) (map[string]any, error)
+	SetUserAttributes(userID int, attributes map[string]any) error
+	SegmentRules() ([]entity.Segment, error)
+	Holdout() (entity.Holdout, error)
 	AddUserSegments(userID int, added []entity.SlugWithExpiredDate) error
 	RemoveUserSegments(userID int, removed []string) error
-	UsersHistoryInByDate(year int, month int) ([]entity.UserSegmentsHistory, error)
-	WriteHistoryToCSV(history []entity.UserSegmentsHistory, year int, month int) (string, error)
+	UpdateUserSegments(userID int, updated []entity.SlugWithExpiredDate) error
+	UsersHistory(filter entity.HistoryFilter) ([]entity.UserSegmentsHistory, error)
+	UserHistory(userID int, afterID int, limit int) ([]entity.UserSegmentsHistory, error)
+	LastUserOperations(userID int, at time.Time) ([]entity.UserSegmentsHistory, error)
+	WriteHistoryToCSV(history []entity.UserSegmentsHistory, name string) (string, error)
 }
 
+const (
+	historyFileTimeLayout = "20060102T150405Z"
+	defaultHistoryLimit   = 50
+	maxHistoryLimit       = 500
+)
+
 type UserUsecase struct {
 	r UserRepo
+	l *logger.Logger
 }
 
-func NewUserUsecase(r UserRepo) *UserUsecase {
-	return &UserUsecase{r}
+func NewUserUsecase(r UserRepo, l *logger.Logger) *UserUsecase {
+	return &UserUsecase{r, l}
 }
 
 func (uc *UserUsecase) RemoveUserSegments(userID int, removed []string) error {
@@ -42,6 +61,20 @@ func (uc *UserUsecase) AddUserSegments(userID int, added []entity.SlugWithExpire
 	return nil
 }
 
+// Changes the expiration of the user memberships, adding the missing ones
+func (uc *UserUsecase) UpdateUserSegments(userID int, updated []entity.SlugWithExpiredDate) error {
+	op := "usecase.user.UpdateUserSegments"
+
+	if err := uc.r.UpdateUserSegments(userID, updated); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Returns explicit memberships of the user along with the segments whose targeting rule matches his attributes.
+// Users in the holdout are only targeted by segments that allow it, a stored rule that can not be parsed
+// is logged and skipped so that it does not break the segments of every user
 func (uc *UserUsecase) UserSegments(userID int) ([]entity.SlugWithExpiredDate, error) {
 	op := "usecase.user.UserSegments"
 
@@ -50,20 +83,198 @@ func (uc *UserUsecase) UserSegments(userID int) ([]entity.SlugWithExpiredDate, e
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	attributes, err := uc.r.UserAttributes(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	targeted, err := uc.r.SegmentRules()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	holdout, err := uc.r.Holdout()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	inHoldout := bucket.InPercent(holdout.Salt, userID, holdout.Percent)
+
+	assigned := make(map[string]struct{}, len(segments))
+	for _, seg := range segments {
+		assigned[seg.Slug] = struct{}{}
+	}
+
+	for _, seg := range targeted {
+		if _, ok := assigned[seg.Slug]; ok {
+			continue
+		}
+		if inHoldout && !seg.AllowHoldout {
+			continue
+		}
+		rule, err := rules.Parse(seg.Rule)
+		if err != nil {
+			uc.l.Error(fmt.Errorf("%s: segment %s: %w: %s", op, seg.Slug, entity.ErrInvalidRule, err))
+			continue
+		}
+		if rule.Match(attributes) {
+			segments = append(segments, entity.SlugWithExpiredDate{
+				Slug:        seg.Slug,
+				ExpiredDate: entity.MaxTime,
+				Targeted:    true,
+			})
+		}
+	}
+
 	return segments, nil
 }
 
-func (uc *UserUsecase) UsersHistoryInCSVByDate(year int, month int) (string, error) {
-	op := "usecase.user.UsersHistoryInCSVByDate"
+// Reconstructs explicit memberships of the user at the given time from the operations history,
+// memberships added before expirations were recorded are marked as having an unknown expiration
+func (uc *UserUsecase) UserSegmentsAt(userID int, at time.Time) ([]entity.SlugWithExpiredDate, error) {
+	op := "usecase.user.UserSegmentsAt"
+
+	if at.After(time.Now()) {
+		return nil, fmt.Errorf("%s: %w", op, entity.ErrFutureTime)
+	}
+
+	operations, err := uc.r.LastUserOperations(userID, at)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var segments []entity.SlugWithExpiredDate
+	for _, operation := range operations {
+		switch operation.Operation {
+		case entity.OperationAdded, entity.OperationExtended, entity.OperationShortened:
+		default:
+			continue
+		}
+		if operation.ExpirationDate == nil {
+			segments = append(segments, entity.SlugWithExpiredDate{
+				Slug:              operation.SegmentSlug,
+				ExpirationUnknown: true,
+			})
+			continue
+		}
+		if !operation.ExpirationDate.After(at) {
+			continue
+		}
+		segments = append(segments, entity.SlugWithExpiredDate{
+			Slug:        operation.SegmentSlug,
+			ExpiredDate: *operation.ExpirationDate,
+		})
+	}
+
+	return segments, nil
+}
+
+func (uc *UserUsecase) UserAttributes(userID int) (map[string]any, error) {
+	op := "usecase.user.UserAttributes"
+
+	attributes, err := uc.r.UserAttributes(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return attributes, nil
+}
+
+// Replaces the user attributes, registered_at is computed and can not be set
+func (uc *UserUsecase) SetUserAttributes(userID int, attributes map[string]any) error {
+	op := "usecase.user.SetUserAttributes"
+
+	delete(attributes, "registered_at")
+	if err := uc.r.SetUserAttributes(userID, attributes); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Returns a page of the user operations after the afterID cursor, oldest first.
+// The returned cursor of the next page is zero when there are no more operations
+func (uc *UserUsecase) UserHistory(userID int, afterID int, limit int) ([]entity.UserSegmentsHistory, int, error) {
+	op := "usecase.user.UserHistory"
+
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+	if afterID < 0 {
+		afterID = 0
+	}
+
+	// one more operation is fetched to know whether there is a next page
+	history, err := uc.r.UserHistory(userID, afterID, limit+1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var next int
+	if len(history) > limit {
+		history = history[:limit]
+		next = history[limit-1].OperationID
+	}
+
+	return history, next, nil
+}
+
+// Writes the operations matching the filter to a CSV file and returns its path.
+// Whole calendar months keep the user_segments_history-<year>-<month> name
+func (uc *UserUsecase) UsersHistoryInCSV(filter entity.HistoryFilter) (string, error) {
+	op := "usecase.user.UsersHistoryInCSV"
+
+	if !validHistoryFilter(filter) {
+		return "", fmt.Errorf("%s: %w", op, entity.ErrInvalidHistoryFilter)
+	}
 
-	history, err := uc.r.UsersHistoryInByDate(year, month)
+	history, err := uc.r.UsersHistory(filter)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	pathToCSV, err := uc.r.WriteHistoryToCSV(history, year, month)
+	pathToCSV, err := uc.r.WriteHistoryToCSV(history, historyFileName(filter))
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 	return pathToCSV, nil
 }
+
+func validHistoryFilter(filter entity.HistoryFilter) bool {
+	if filter.From.IsZero() || !filter.From.Before(filter.To) {
+		return false
+	}
+	for _, operation := range filter.Operations {
+		switch operation {
+		case entity.OperationAdded, entity.OperationRemoved, entity.OperationExtended, entity.OperationShortened,
+			entity.OperationExpired:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Name of the CSV file, the other filters are hashed so that different exports of a range do not overwrite each other
+func historyFileName(filter entity.HistoryFilter) string {
+	from, to := filter.From.UTC(), filter.To.UTC()
+
+	var name string
+	if from.Equal(time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)) && to.Equal(from.AddDate(0, 1, 0)) {
+		name = fmt.Sprintf("user_segments_history-%d-%d", from.Year(), from.Month())
+	} else {
+		name = fmt.Sprintf("user_segments_history-%s-%s", from.Format(historyFileTimeLayout), to.Format(historyFileTimeLayout))
+	}
+
+	if filter.UserID == nil && filter.SegmentSlug == "" && len(filter.Operations) == 0 {
+		return name
+	}
+	h := fnv.New32a()
+	if filter.UserID != nil {
+		fmt.Fprintf(h, "user:%d;", *filter.UserID)
+	}
+	fmt.Fprintf(h, "segment:%s;operations:%v", filter.SegmentSlug, filter.Operations)
+	return fmt.Sprintf("%s-%08x", name, h.Sum32())
+}
@@ -6,13 +6,14 @@ import (
 
 	"experiment.io/internal/entity"
 	"experiment.io/internal/mocks"
+	"experiment.io/pkg/logger"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
 func TestRemoveUserSegments(t *testing.T) {
 	r := new(mocks.UserRepo)
-	uc := NewUserUsecase(r)
+	uc := NewUserUsecase(r, logger.New())
 
 	testCase := []struct {
 		name        string
@@ -74,7 +75,7 @@ func TestRemoveUserSegments(t *testing.T) {
 
 func TestAddUserSegments(t *testing.T) {
 	r := new(mocks.UserRepo)
-	uc := NewUserUsecase(r)
+	uc := NewUserUsecase(r, logger.New())
 
 	testCase := []struct {
 		name        string
@@ -126,25 +127,133 @@ func TestAddUserSegments(t *testing.T) {
 	}
 }
 
+func TestUpdateUserSegments(t *testing.T) {
+	r := new(mocks.UserRepo)
+	uc := NewUserUsecase(r, logger.New())
+
+	testCase := []struct {
+		name        string
+		userID      int
+		updated     []entity.SlugWithExpiredDate
+		repoErr     error
+		expectedErr error
+	}{
+		{
+			name: "Extend memberships",
+			updated: []entity.SlugWithExpiredDate{
+				{Slug: "Segment1", ExpiredDate: time.Now().Add(24 * time.Hour)},
+				{Slug: "Segment2", ExpiredDate: time.Now().Add(48 * time.Hour)},
+			},
+			userID:      1,
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name: "Non-existent user",
+			updated: []entity.SlugWithExpiredDate{
+				{Slug: "Segment1", ExpiredDate: time.Now().Add(time.Hour)},
+			},
+			userID:      0,
+			repoErr:     entity.ErrUserNotFound,
+			expectedErr: entity.ErrUserNotFound,
+		},
+		{
+			name: "Added segment is full",
+			updated: []entity.SlugWithExpiredDate{
+				{Slug: "LimitedSegment", ExpiredDate: time.Now().Add(time.Hour)},
+			},
+			userID:      1,
+			repoErr:     entity.ErrSegmentFull,
+			expectedErr: entity.ErrSegmentFull,
+		},
+		{
+			name: "Expired membership of a full segment",
+			updated: []entity.SlugWithExpiredDate{
+				{Slug: "LimitedSegment", ExpiredDate: time.Now().Add(24 * time.Hour)},
+			},
+			userID:      2,
+			repoErr:     entity.ErrSegmentFull,
+			expectedErr: entity.ErrSegmentFull,
+		},
+		{
+			name: "Archived segment",
+			updated: []entity.SlugWithExpiredDate{
+				{Slug: "ArchivedSegment", ExpiredDate: time.Now().Add(24 * time.Hour)},
+			},
+			userID:      1,
+			repoErr:     entity.ErrSegmentArchived,
+			expectedErr: entity.ErrSegmentArchived,
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("UpdateUserSegments", tc.userID, tc.updated).Return(tc.repoErr)
+
+			err := uc.UpdateUserSegments(tc.userID, tc.updated)
+			require.ErrorIs(t, err, tc.expectedErr)
+
+			mockCall.Unset()
+		})
+	}
+}
+
 func TestUserSegments(t *testing.T) {
 	r := new(mocks.UserRepo)
-	uc := NewUserUsecase(r)
+	uc := NewUserUsecase(r, logger.New())
+
+	expiredDate := time.Now().Add(time.Hour)
+	rules := []entity.Segment{
+		{Slug: "Segment1", Rule: `country == "RU"`},
+		{Slug: "IOS_USERS", Rule: `platform == "ios"`},
+		{Slug: "KZ_USERS", Rule: `country == "KZ"`},
+		{Slug: "ALL_IOS_USERS", Rule: `platform == "ios"`, AllowHoldout: true},
+		{Slug: "BROKEN_RULE", Rule: `platform ==`},
+	}
 
 	testCase := []struct {
-		name         string
-		userID       int
-		repoSegments []entity.SlugWithExpiredDate
-		repoErr      error
-		expectedErr  error
+		name             string
+		userID           int
+		repoSegments     []entity.SlugWithExpiredDate
+		repoErr          error
+		repoAttributes   map[string]any
+		repoAttrErr      error
+		repoHoldout      entity.Holdout
+		expectedSegments []entity.SlugWithExpiredDate
+		expectedErr      error
 	}{
 		{
 			name:   "Get segments for existent user",
 			userID: 1,
 			repoSegments: []entity.SlugWithExpiredDate{
-				{Slug: "Segment1", ExpiredDate: time.Now().Add(time.Hour)},
-				{Slug: "Segment2", ExpiredDate: time.Now().Add(2 * time.Hour)},
+				{Slug: "Segment1", ExpiredDate: expiredDate},
+				{Slug: "Segment2", ExpiredDate: expiredDate},
+			},
+			repoErr:        nil,
+			repoAttributes: map[string]any{"country": "RU", "platform": "ios"},
+			repoAttrErr:    nil,
+			expectedSegments: []entity.SlugWithExpiredDate{
+				{Slug: "Segment1", ExpiredDate: expiredDate},
+				{Slug: "Segment2", ExpiredDate: expiredDate},
+				{Slug: "IOS_USERS", ExpiredDate: entity.MaxTime, Targeted: true},
+				{Slug: "ALL_IOS_USERS", ExpiredDate: entity.MaxTime, Targeted: true},
+			},
+			expectedErr: nil,
+		},
+		{
+			name:   "User in holdout is targeted only by segments allowing it",
+			userID: 1,
+			repoSegments: []entity.SlugWithExpiredDate{
+				{Slug: "Segment2", ExpiredDate: expiredDate},
+			},
+			repoErr:        nil,
+			repoAttributes: map[string]any{"country": "RU", "platform": "ios"},
+			repoAttrErr:    nil,
+			repoHoldout:    entity.Holdout{Salt: "holdout", Percent: 100},
+			expectedSegments: []entity.SlugWithExpiredDate{
+				{Slug: "Segment2", ExpiredDate: expiredDate},
+				{Slug: "ALL_IOS_USERS", ExpiredDate: entity.MaxTime, Targeted: true},
 			},
-			repoErr:     nil,
 			expectedErr: nil,
 		},
 		{
@@ -154,11 +263,23 @@ func TestUserSegments(t *testing.T) {
 			repoErr:      entity.ErrUserNotFound,
 			expectedErr:  entity.ErrUserNotFound,
 		},
+		{
+			name:           "Attributes of non-existent user",
+			userID:         0,
+			repoSegments:   nil,
+			repoErr:        nil,
+			repoAttributes: nil,
+			repoAttrErr:    entity.ErrUserNotFound,
+			expectedErr:    entity.ErrUserNotFound,
+		},
 	}
 
 	for _, tc := range testCase {
 		t.Run(tc.name, func(t *testing.T) {
 			mockCall := r.On("UserSegments", tc.userID).Return(tc.repoSegments, tc.repoErr)
+			mockAttrCall := r.On("UserAttributes", tc.userID).Return(tc.repoAttributes, tc.repoAttrErr)
+			mockRulesCall := r.On("SegmentRules").Return(rules, nil)
+			mockHoldoutCall := r.On("Holdout").Return(tc.repoHoldout, nil)
 
 			segments, err := uc.UserSegments(tc.userID)
 			if tc.expectedErr != nil {
@@ -167,60 +288,389 @@ func TestUserSegments(t *testing.T) {
 			} else {
 				require.NoError(t, err)
 				require.NotNil(t, segments)
-				require.Equal(t, tc.repoSegments, segments)
+				require.Equal(t, tc.expectedSegments, segments)
 			}
 
+			mockCall.Unset()
+			mockAttrCall.Unset()
+			mockRulesCall.Unset()
+			mockHoldoutCall.Unset()
+		})
+	}
+}
+
+func TestSetUserAttributes(t *testing.T) {
+	r := new(mocks.UserRepo)
+	uc := NewUserUsecase(r, logger.New())
+
+	testCase := []struct {
+		name        string
+		userID      int
+		attributes  map[string]any
+		repoAttrs   map[string]any
+		repoErr     error
+		expectedErr error
+	}{
+		{
+			name:        "Success",
+			userID:      1,
+			attributes:  map[string]any{"country": "RU"},
+			repoAttrs:   map[string]any{"country": "RU"},
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name:        "Registration date can not be set",
+			userID:      1,
+			attributes:  map[string]any{"country": "RU", "registered_at": "2007-01-01"},
+			repoAttrs:   map[string]any{"country": "RU"},
+			repoErr:     nil,
+			expectedErr: nil,
+		},
+		{
+			name:        "Non-existent user",
+			userID:      0,
+			attributes:  map[string]any{"country": "RU"},
+			repoAttrs:   map[string]any{"country": "RU"},
+			repoErr:     entity.ErrUserNotFound,
+			expectedErr: entity.ErrUserNotFound,
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("SetUserAttributes", tc.userID, tc.repoAttrs).Return(tc.repoErr)
+
+			err := uc.SetUserAttributes(tc.userID, tc.attributes)
+			require.ErrorIs(t, err, tc.expectedErr)
+
+			mockCall.Unset()
+		})
+	}
+}
+
+func TestUserSegmentsAt(t *testing.T) {
+	r := new(mocks.UserRepo)
+	uc := NewUserUsecase(r, logger.New())
+
+	at := time.Date(2023, 8, 15, 12, 0, 0, 0, time.UTC)
+	later := at.Add(24 * time.Hour)
+	earlier := at.Add(-time.Hour)
+	infinity := entity.MaxTime
+
+	testCases := []struct {
+		name             string
+		at               time.Time
+		operations       []entity.UserSegmentsHistory
+		repoErr          error
+		expectedSegments []entity.SlugWithExpiredDate
+		expectedErr      error
+	}{
+		{
+			name: "Added, extended and shortened memberships",
+			at:   at,
+			operations: []entity.UserSegmentsHistory{
+				{SegmentSlug: "AVITO_DISCOUNT_30", Operation: entity.OperationAdded, ExpirationDate: &later},
+				{SegmentSlug: "AVITO_DISCOUNT_50", Operation: entity.OperationExtended, ExpirationDate: &infinity},
+				{SegmentSlug: "AVITO_PERFORMANCE_VAS", Operation: entity.OperationShortened, ExpirationDate: &later},
+			},
+			repoErr: nil,
+			expectedSegments: []entity.SlugWithExpiredDate{
+				{Slug: "AVITO_DISCOUNT_30", ExpiredDate: later},
+				{Slug: "AVITO_DISCOUNT_50", ExpiredDate: infinity},
+				{Slug: "AVITO_PERFORMANCE_VAS", ExpiredDate: later},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "Removed and expired memberships",
+			at:   at,
+			operations: []entity.UserSegmentsHistory{
+				{SegmentSlug: "AVITO_DISCOUNT_30", Operation: entity.OperationRemoved, ExpirationDate: &later},
+				{SegmentSlug: "AVITO_DISCOUNT_50", Operation: entity.OperationExpired, ExpirationDate: &earlier},
+			},
+			repoErr:          nil,
+			expectedSegments: nil,
+			expectedErr:      nil,
+		},
+		{
+			name: "Membership expired before the time but not removed yet",
+			at:   at,
+			operations: []entity.UserSegmentsHistory{
+				{SegmentSlug: "AVITO_DISCOUNT_30", Operation: entity.OperationAdded, ExpirationDate: &earlier},
+				{SegmentSlug: "AVITO_DISCOUNT_50", Operation: entity.OperationAdded, ExpirationDate: &at},
+			},
+			repoErr:          nil,
+			expectedSegments: nil,
+			expectedErr:      nil,
+		},
+		{
+			name: "Operation recorded before expirations were tracked",
+			at:   at,
+			operations: []entity.UserSegmentsHistory{
+				{SegmentSlug: "AVITO_DISCOUNT_30", Operation: entity.OperationAdded},
+				{SegmentSlug: "AVITO_DISCOUNT_50", Operation: entity.OperationAdded, ExpirationDate: &later},
+			},
+			repoErr: nil,
+			expectedSegments: []entity.SlugWithExpiredDate{
+				{Slug: "AVITO_DISCOUNT_30", ExpirationUnknown: true},
+				{Slug: "AVITO_DISCOUNT_50", ExpiredDate: later},
+			},
+			expectedErr: nil,
+		},
+		{
+			name:             "Non-existent user",
+			at:               at,
+			operations:       nil,
+			repoErr:          entity.ErrUserNotFound,
+			expectedSegments: nil,
+			expectedErr:      entity.ErrUserNotFound,
+		},
+		{
+			name:             "Future time",
+			at:               time.Now().Add(time.Hour),
+			operations:       nil,
+			repoErr:          nil,
+			expectedSegments: nil,
+			expectedErr:      entity.ErrFutureTime,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("LastUserOperations", 1, tc.at).Return(tc.operations, tc.repoErr)
+
+			segments, err := uc.UserSegmentsAt(1, tc.at)
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expectedSegments, segments)
+
 			mockCall.Unset()
 		})
 	}
 }
 
-func TestUsersHistoryInCSVByDate(t *testing.T) {
+func TestUserHistory(t *testing.T) {
 	r := new(mocks.UserRepo)
-	uc := NewUserUsecase(r)
+	uc := NewUserUsecase(r, logger.New())
+
+	operations := func(ids ...int) []entity.UserSegmentsHistory {
+		history := make([]entity.UserSegmentsHistory, len(ids))
+		for i, id := range ids {
+			history[i] = entity.UserSegmentsHistory{OperationID: id, UserID: 1, SegmentSlug: "AVITO_DISCOUNT_30", Operation: entity.OperationAdded}
+		}
+		return history
+	}
+
+	testCases := []struct {
+		name            string
+		afterID         int
+		limit           int
+		repoAfterID     int
+		repoLimit       int
+		repoHistory     []entity.UserSegmentsHistory
+		repoErr         error
+		expectedHistory []entity.UserSegmentsHistory
+		expectedNext    int
+		expectedErr     error
+	}{
+		{
+			name:            "Last page",
+			afterID:         0,
+			limit:           3,
+			repoAfterID:     0,
+			repoLimit:       4,
+			repoHistory:     operations(1, 5),
+			repoErr:         nil,
+			expectedHistory: operations(1, 5),
+			expectedNext:    0,
+			expectedErr:     nil,
+		},
+		{
+			name:            "Page with a next one",
+			afterID:         5,
+			limit:           2,
+			repoAfterID:     5,
+			repoLimit:       3,
+			repoHistory:     operations(7, 9, 12),
+			repoErr:         nil,
+			expectedHistory: operations(7, 9),
+			expectedNext:    9,
+			expectedErr:     nil,
+		},
+		{
+			name:            "Default limit",
+			afterID:         0,
+			limit:           0,
+			repoAfterID:     0,
+			repoLimit:       defaultHistoryLimit + 1,
+			repoHistory:     nil,
+			repoErr:         nil,
+			expectedHistory: nil,
+			expectedNext:    0,
+			expectedErr:     nil,
+		},
+		{
+			name:            "Limit above the maximum",
+			afterID:         -1,
+			limit:           maxHistoryLimit + 1,
+			repoAfterID:     0,
+			repoLimit:       maxHistoryLimit + 1,
+			repoHistory:     nil,
+			repoErr:         nil,
+			expectedHistory: nil,
+			expectedNext:    0,
+			expectedErr:     nil,
+		},
+		{
+			name:            "Non-existent user",
+			afterID:         0,
+			limit:           10,
+			repoAfterID:     0,
+			repoLimit:       11,
+			repoHistory:     nil,
+			repoErr:         entity.ErrUserNotFound,
+			expectedHistory: nil,
+			expectedNext:    0,
+			expectedErr:     entity.ErrUserNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCall := r.On("UserHistory", 1, tc.repoAfterID, tc.repoLimit).Return(tc.repoHistory, tc.repoErr)
+
+			history, next, err := uc.UserHistory(1, tc.afterID, tc.limit)
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expectedHistory, history)
+			require.Equal(t, tc.expectedNext, next)
+
+			mockCall.Unset()
+		})
+	}
+}
+
+func TestUsersHistoryInCSV(t *testing.T) {
+	r := new(mocks.UserRepo)
+	uc := NewUserUsecase(r, logger.New())
+
+	august := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+	userID := 1
 
 	testCases := []struct {
 		name        string
-		year        int
-		month       int
+		filter      entity.HistoryFilter
 		fetchErr    error
 		writeErr    error
 		expectedErr error
 	}{
 		{
 			name:        "Success",
-			year:        2023,
-			month:       8,
+			filter:      entity.HistoryFilter{From: august, To: august.AddDate(0, 1, 0)},
+			fetchErr:    nil,
+			writeErr:    nil,
+			expectedErr: nil,
+		},
+		{
+			name: "Success with filters",
+			filter: entity.HistoryFilter{
+				From:        august,
+				To:          august.Add(36 * time.Hour),
+				UserID:      &userID,
+				SegmentSlug: "AVITO_DISCOUNT_30",
+				Operations:  []entity.UserOperation{entity.OperationAdded, entity.OperationExpired},
+			},
 			fetchErr:    nil,
 			writeErr:    nil,
 			expectedErr: nil,
 		},
 		{
 			name:        "Error fetching history",
-			year:        2023,
-			month:       8,
+			filter:      entity.HistoryFilter{From: august, To: august.AddDate(0, 1, 0)},
 			fetchErr:    entity.ErrInternalServer,
 			writeErr:    nil,
 			expectedErr: entity.ErrInternalServer,
 		},
 		{
 			name:        "Error writing CSV",
-			year:        2023,
-			month:       8,
+			filter:      entity.HistoryFilter{From: august, To: august.AddDate(0, 1, 0)},
 			fetchErr:    entity.ErrInternalServer,
 			writeErr:    entity.ErrInternalServer,
 			expectedErr: entity.ErrInternalServer,
 		},
+		{
+			name:        "Empty range",
+			filter:      entity.HistoryFilter{From: august, To: august},
+			fetchErr:    nil,
+			writeErr:    nil,
+			expectedErr: entity.ErrInvalidHistoryFilter,
+		},
+		{
+			name:        "Missing from",
+			filter:      entity.HistoryFilter{To: august},
+			fetchErr:    nil,
+			writeErr:    nil,
+			expectedErr: entity.ErrInvalidHistoryFilter,
+		},
+		{
+			name: "Unknown operation",
+			filter: entity.HistoryFilter{
+				From:       august,
+				To:         august.AddDate(0, 1, 0),
+				Operations: []entity.UserOperation{"renamed"},
+			},
+			fetchErr:    nil,
+			writeErr:    nil,
+			expectedErr: entity.ErrInvalidHistoryFilter,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockCall := r.On("UsersHistoryInByDate", tc.year, tc.month).Return([]entity.UserSegmentsHistory{}, tc.fetchErr)
-			mockCall.On("WriteHistoryToCSV", mock.Anything, mock.Anything, mock.Anything).Return("", tc.writeErr)
+			mockCall := r.On("UsersHistory", tc.filter).Return([]entity.UserSegmentsHistory{}, tc.fetchErr)
+			writeCall := r.On("WriteHistoryToCSV", mock.Anything, mock.Anything).Return("", tc.writeErr)
 
-			_, err := uc.UsersHistoryInCSVByDate(tc.year, tc.month)
+			_, err := uc.UsersHistoryInCSV(tc.filter)
 			require.ErrorIs(t, err, tc.expectedErr)
 			mockCall.Unset()
+			writeCall.Unset()
+		})
+	}
+}
+
+func TestHistoryFileName(t *testing.T) {
+	august := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+	userID := 1
+
+	testCases := []struct {
+		name     string
+		filter   entity.HistoryFilter
+		expected string
+	}{
+		{
+			name:     "Calendar month",
+			filter:   entity.HistoryFilter{From: august, To: august.AddDate(0, 1, 0)},
+			expected: "user_segments_history-2023-8",
+		},
+		{
+			name:     "Calendar month with an offset",
+			filter:   entity.HistoryFilter{From: august.In(time.FixedZone("MSK", 3*60*60)), To: august.AddDate(0, 1, 0)},
+			expected: "user_segments_history-2023-8",
+		},
+		{
+			name:     "Arbitrary range",
+			filter:   entity.HistoryFilter{From: august.Add(time.Hour), To: august.AddDate(0, 0, 2)},
+			expected: "user_segments_history-20230801T010000Z-20230803T000000Z",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, historyFileName(tc.filter))
 		})
 	}
+
+	byUser := historyFileName(entity.HistoryFilter{From: august, To: august.AddDate(0, 1, 0), UserID: &userID})
+	bySlug := historyFileName(entity.HistoryFilter{From: august, To: august.AddDate(0, 1, 0), SegmentSlug: "AVITO_DISCOUNT_30"})
+	require.Regexp(t, `^user_segments_history-2023-8-[0-9a-f]{8}$`, byUser)
+	require.NotEqual(t, byUser, bySlug)
 }
@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"experiment.io/pkg/logger"
+)
+
+type ExpiryReaperUsecase interface {
+	RemoveExpiredMemberships() (int, error)
+}
+
+// Periodically removes expired memberships so that they are recorded in the operations history
+type ExpiryReaper struct {
+	uc       ExpiryReaperUsecase
+	l        *logger.Logger
+	interval time.Duration
+}
+
+func NewExpiryReaper(uc ExpiryReaperUsecase, l *logger.Logger, interval time.Duration) *ExpiryReaper {
+	return &ExpiryReaper{uc, l, interval}
+}
+
+// Removes the memberships expired while the service was down and then every interval until ctx is done
+func (r *ExpiryReaper) Run(ctx context.Context) {
+	Periodic(ctx, r.interval, r.removeExpired)
+}
+
+func (r *ExpiryReaper) removeExpired() {
+	removed, err := r.uc.RemoveExpiredMemberships()
+	if removed > 0 {
+		r.l.Info(fmt.Sprintf("%d expired memberships removed", removed))
+	}
+	if err != nil {
+		r.l.Error(err)
+	}
+}
@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"experiment.io/internal/entity"
+	"experiment.io/internal/mocks"
+	"experiment.io/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiryReaperRemoveExpired(t *testing.T) {
+	testCases := []struct {
+		name         string
+		removed      int
+		errUC        error
+		expectedLogs string
+	}{
+		{
+			name:         "Logs the number of removed memberships",
+			removed:      5,
+			errUC:        nil,
+			expectedLogs: "5 expired memberships removed",
+		},
+		{
+			name:         "Nothing expired",
+			removed:      0,
+			errUC:        nil,
+			expectedLogs: "",
+		},
+		{
+			name:         "Logs a usecase error",
+			removed:      0,
+			errUC:        entity.ErrInternalServer,
+			expectedLogs: entity.ErrInternalServer.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUsecase := new(mocks.ExpiryReaperUsecase)
+			mockUsecase.On("RemoveExpiredMemberships").Return(tc.removed, tc.errUC)
+			var logs bytes.Buffer
+			l := logger.New()
+			l.SetOutput(&logs)
+
+			NewExpiryReaper(mockUsecase, l, time.Minute).removeExpired()
+
+			mockUsecase.AssertNumberOfCalls(t, "RemoveExpiredMemberships", 1)
+			if tc.expectedLogs == "" {
+				require.Empty(t, logs.String())
+				return
+			}
+			require.Contains(t, logs.String(), tc.expectedLogs)
+		})
+	}
+}
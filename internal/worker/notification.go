@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"experiment.io/pkg/logger"
+)
+
+type NotificationUsecase interface {
+	NotifyExpiringMemberships() (int, error)
+}
+
+// Periodically notifies about memberships that are about to expire
+type NotificationScheduler struct {
+	uc       NotificationUsecase
+	l        *logger.Logger
+	interval time.Duration
+}
+
+func NewNotificationScheduler(uc NotificationUsecase, l *logger.Logger, interval time.Duration) *NotificationScheduler {
+	return &NotificationScheduler{uc, l, interval}
+}
+
+// Sends the pending notifications on start and then every interval until ctx is done
+func (s *NotificationScheduler) Run(ctx context.Context) {
+	Periodic(ctx, s.interval, s.notify)
+}
+
+func (s *NotificationScheduler) notify() {
+	sent, err := s.uc.NotifyExpiringMemberships()
+	if sent > 0 {
+		s.l.Info(fmt.Sprintf("%d expiry notifications sent", sent))
+	}
+	if err != nil {
+		s.l.Error(err)
+	}
+}
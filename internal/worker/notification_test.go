@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"experiment.io/internal/entity"
+	"experiment.io/internal/mocks"
+	"experiment.io/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationSchedulerNotify(t *testing.T) {
+	testCases := []struct {
+		name         string
+		sent         int
+		errUC        error
+		expectedLogs []string
+	}{
+		{
+			name:         "Logs the number of sent notifications",
+			sent:         2,
+			errUC:        nil,
+			expectedLogs: []string{"2 expiry notifications sent"},
+		},
+		{
+			name:         "Nothing to notify",
+			sent:         0,
+			errUC:        nil,
+			expectedLogs: nil,
+		},
+		{
+			name:         "Logs the sent notifications and the failed ones",
+			sent:         1,
+			errUC:        entity.ErrNotificationRejected,
+			expectedLogs: []string{"1 expiry notifications sent", entity.ErrNotificationRejected.Error()},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUsecase := new(mocks.NotificationUsecase)
+			mockUsecase.On("NotifyExpiringMemberships").Return(tc.sent, tc.errUC)
+			var logs bytes.Buffer
+			l := logger.New()
+			l.SetOutput(&logs)
+
+			NewNotificationScheduler(mockUsecase, l, time.Minute).notify()
+
+			mockUsecase.AssertNumberOfCalls(t, "NotifyExpiringMemberships", 1)
+			if len(tc.expectedLogs) == 0 {
+				require.Empty(t, logs.String())
+			}
+			for _, expected := range tc.expectedLogs {
+				require.Contains(t, logs.String(), expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeriodic(t *testing.T) {
+	testCases := []struct {
+		name     string
+		timeout  time.Duration
+		minCalls int
+		maxCalls int
+	}{
+		{
+			name:     "Runs the job on start",
+			timeout:  0,
+			minCalls: 1,
+			maxCalls: 1,
+		},
+		{
+			name:     "Runs the job every interval until canceled",
+			timeout:  55 * time.Millisecond,
+			minCalls: 2,
+			maxCalls: 6,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+
+			calls := 0
+			Periodic(ctx, 10*time.Millisecond, func() { calls++ })
+
+			require.GreaterOrEqual(t, calls, tc.minCalls)
+			require.LessOrEqual(t, calls, tc.maxCalls)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"experiment.io/internal/entity"
+	"experiment.io/pkg/logger"
+)
+
+type RolloutUsecase interface {
+	ApplyDueRolloutSteps() ([]entity.RolloutStep, error)
+}
+
+// Periodically applies the due steps of segment rollout schedules
+type RolloutScheduler struct {
+	uc       RolloutUsecase
+	l        *logger.Logger
+	interval time.Duration
+}
+
+func NewRolloutScheduler(uc RolloutUsecase, l *logger.Logger, interval time.Duration) *RolloutScheduler {
+	return &RolloutScheduler{uc, l, interval}
+}
+
+// Applies the steps missed while the service was down and then every interval until ctx is done
+func (s *RolloutScheduler) Run(ctx context.Context) {
+	Periodic(ctx, s.interval, s.applyDueSteps)
+}
+
+func (s *RolloutScheduler) applyDueSteps() {
+	steps, err := s.uc.ApplyDueRolloutSteps()
+	for _, step := range steps {
+		if step.AppliedAt == nil {
+			s.l.Error(fmt.Sprintf("rollout step %d failed, attempt %d: segment %s: %s",
+				step.ID, step.Attempts, step.SegmentSlug, step.LastError))
+			continue
+		}
+		s.l.Info(fmt.Sprintf("rollout step %d applied: segment %s set to %d%%", step.ID, step.SegmentSlug, step.Percent))
+	}
+	if err != nil {
+		s.l.Error(err)
+	}
+}
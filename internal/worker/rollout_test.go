@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"experiment.io/internal/entity"
+	"experiment.io/internal/mocks"
+	"experiment.io/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRolloutSchedulerApplyDueSteps(t *testing.T) {
+	appliedAt := time.Date(2023, time.September, 18, 10, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name         string
+		steps        []entity.RolloutStep
+		errUC        error
+		expectedLogs []string
+	}{
+		{
+			name: "Logs applied and failed steps",
+			steps: []entity.RolloutStep{
+				{ID: 1, SegmentSlug: "variant", Percent: 10, Attempts: 1, LastError: entity.ErrNotRolloutSegment.Error()},
+				{ID: 2, SegmentSlug: "slug", Percent: 10, AppliedAt: &appliedAt},
+			},
+			errUC: nil,
+			expectedLogs: []string{
+				"rollout step 1 failed, attempt 1: segment variant: " + entity.ErrNotRolloutSegment.Error(),
+				"rollout step 2 applied: segment slug set to 10%",
+			},
+		},
+		{
+			name:         "Logs the steps applied before a usecase error",
+			steps:        []entity.RolloutStep{{ID: 2, SegmentSlug: "slug", Percent: 10, AppliedAt: &appliedAt}},
+			errUC:        entity.ErrInternalServer,
+			expectedLogs: []string{"rollout step 2 applied", entity.ErrInternalServer.Error()},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUsecase := new(mocks.RolloutUsecase)
+			mockUsecase.On("ApplyDueRolloutSteps").Return(tc.steps, tc.errUC)
+			var logs bytes.Buffer
+			l := logger.New()
+			l.SetOutput(&logs)
+
+			NewRolloutScheduler(mockUsecase, l, time.Minute).applyDueSteps()
+
+			for _, expected := range tc.expectedLogs {
+				require.Contains(t, logs.String(), expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+DROP FUNCTION IF EXISTS create_segment_and_add_users(VARCHAR, DECIMAL, BOOLEAN, VARCHAR);
+
+CREATE OR REPLACE FUNCTION create_segment_and_add_users(new_slug VARCHAR(100), target_percent DECIMAL)
+RETURNS TABLE (user_id INTEGER, segment_created BOOLEAN) AS
+$$
+DECLARE
+    users_to_add INTEGER;
+BEGIN
+    IF EXISTS (SELECT 1 FROM segments WHERE slug = new_slug) THEN
+        segment_created := FALSE;
+        RETURN QUERY SELECT -1, FALSE;
+    ELSE
+        INSERT INTO segments (slug) VALUES (new_slug);
+        segment_created := TRUE;
+    END IF;
+
+    users_to_add := ROUND((SELECT COUNT(*) FROM users) * (target_percent / 100));
+    IF segment_created = TRUE THEN
+    FOR user_id IN
+        SELECT id
+        FROM users
+        WHERE id NOT IN (SELECT segments_to_users.user_id FROM segments_to_users WHERE segment_slug = new_slug)
+        ORDER BY random()
+        LIMIT users_to_add
+    LOOP
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (new_slug, user_id, 'INFINITY');
+
+        RETURN NEXT;
+    END LOOP;
+	END IF;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
+
+DROP FUNCTION IF EXISTS user_bucket(VARCHAR, INTEGER);
+ALTER TABLE segments DROP COLUMN IF EXISTS salt;
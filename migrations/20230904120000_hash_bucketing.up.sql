@@ -0,0 +1,61 @@
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS salt VARCHAR(100);
+
+-- Deterministic bucket of the user in [0, 10000), mirrors pkg/bucket
+CREATE OR REPLACE FUNCTION user_bucket(bucket_salt VARCHAR(100), bucket_user_id INTEGER)
+RETURNS INTEGER AS
+$$
+    SELECT (('x' || substr(md5(bucket_salt || ':' || bucket_user_id::TEXT), 1, 8))::BIT(32)::BIGINT % 10000)::INTEGER;
+$$
+LANGUAGE SQL IMMUTABLE;
+
+DROP FUNCTION IF EXISTS create_segment_and_add_users(VARCHAR, DECIMAL);
+
+-- Function for automatically assigning segments to users.
+-- In deterministic mode a user is assigned when his bucket is less than target_percent * 100
+CREATE OR REPLACE FUNCTION create_segment_and_add_users(new_slug VARCHAR(100), target_percent DECIMAL,
+    deterministic BOOLEAN, segment_salt VARCHAR(100))
+RETURNS TABLE (user_id INTEGER, segment_created BOOLEAN) AS
+$$
+DECLARE
+    users_to_add INTEGER;
+BEGIN
+    IF EXISTS (SELECT 1 FROM segments WHERE slug = new_slug) THEN
+        segment_created := FALSE;
+        RETURN QUERY SELECT -1, FALSE;
+    ELSE
+        INSERT INTO segments (slug, salt) VALUES (new_slug, segment_salt);
+        segment_created := TRUE;
+    END IF;
+
+    IF segment_created = TRUE AND deterministic = TRUE THEN
+    FOR user_id IN
+        SELECT id
+        FROM users
+        WHERE user_bucket(segment_salt, id) < target_percent * 100
+        ORDER BY id
+    LOOP
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (new_slug, user_id, 'INFINITY');
+
+        RETURN NEXT;
+    END LOOP;
+    ELSIF segment_created = TRUE THEN
+    users_to_add := ROUND((SELECT COUNT(*) FROM users) * (target_percent / 100));
+    FOR user_id IN
+        SELECT id
+        FROM users
+        WHERE id NOT IN (SELECT segments_to_users.user_id FROM segments_to_users WHERE segment_slug = new_slug)
+        ORDER BY random()
+        LIMIT users_to_add
+    LOOP
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (new_slug, user_id, 'INFINITY');
+
+        RETURN NEXT;
+    END LOOP;
+    END IF;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
@@ -0,0 +1,58 @@
+DROP TRIGGER IF EXISTS segments_to_users_guard_trigger ON segments_to_users;
+DROP FUNCTION IF EXISTS guard_segment_assignment();
+DROP FUNCTION IF EXISTS assignment_violation(VARCHAR, INTEGER);
+
+-- Enrolls the user into every percentage segment whose rule matches him.
+-- Deterministic segments compare the user bucket, the others add the user
+-- while the number of active members is below the configured share of all users
+CREATE OR REPLACE FUNCTION enroll_user_in_rollouts(enrolled_user_id INTEGER)
+RETURNS TABLE (segment_slug VARCHAR(100)) AS
+$$
+DECLARE
+    seg RECORD;
+    users_count INTEGER;
+    members_count INTEGER;
+BEGIN
+    users_count := (SELECT COUNT(*) FROM users);
+
+    FOR seg IN
+        SELECT slug, salt, rollout_percent, deterministic
+        FROM segments
+        WHERE rollout_percent IS NOT NULL
+        ORDER BY slug
+    LOOP
+        IF EXISTS (SELECT 1 FROM segments_to_users s
+                   WHERE s.segment_slug = seg.slug AND s.user_id = enrolled_user_id) THEN
+            CONTINUE;
+        END IF;
+
+        IF seg.deterministic THEN
+            IF user_bucket(seg.salt, enrolled_user_id) >= seg.rollout_percent * 100 THEN
+                CONTINUE;
+            END IF;
+        ELSE
+            members_count := (SELECT COUNT(*) FROM segments_to_users s
+                              WHERE s.segment_slug = seg.slug AND s.expiration_date > NOW());
+            IF members_count >= ROUND(users_count * (seg.rollout_percent / 100)) THEN
+                CONTINUE;
+            END IF;
+        END IF;
+
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (seg.slug, enrolled_user_id, 'INFINITY');
+
+        segment_slug := seg.slug;
+        RETURN NEXT;
+    END LOOP;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
+
+DROP FUNCTION IF EXISTS experiment_variant(VARCHAR, INTEGER);
+ALTER TABLE segments DROP CONSTRAINT IF EXISTS segments_experiment_variant_key;
+ALTER TABLE segments DROP COLUMN IF EXISTS variant_weight;
+ALTER TABLE segments DROP COLUMN IF EXISTS variant;
+ALTER TABLE segments DROP COLUMN IF EXISTS experiment_slug;
+DROP TABLE IF EXISTS experiments;
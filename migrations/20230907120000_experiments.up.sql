@@ -0,0 +1,144 @@
+CREATE TABLE IF NOT EXISTS experiments (
+    slug VARCHAR(100) PRIMARY KEY,
+    salt VARCHAR(100) NOT NULL
+);
+
+-- Every variant of an experiment is backed by its own segment
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS experiment_slug VARCHAR(100) REFERENCES experiments(slug) ON DELETE CASCADE;
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS variant VARCHAR(100);
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS variant_weight INTEGER;
+ALTER TABLE segments ADD CONSTRAINT segments_experiment_variant_key UNIQUE (experiment_slug, variant);
+
+-- Returns the slug of the variant segment the user falls into.
+-- Variants split the bucket space proportionally to their weights in slug order
+CREATE OR REPLACE FUNCTION experiment_variant(exp_slug VARCHAR(100), variant_user_id INTEGER)
+RETURNS VARCHAR(100) AS
+$$
+    SELECT v.slug
+    FROM (
+        SELECT slug,
+               SUM(variant_weight) OVER (ORDER BY slug) AS upper_weight,
+               SUM(variant_weight) OVER () AS total_weight
+        FROM segments
+        WHERE experiment_slug = exp_slug
+    ) v, experiments e
+    WHERE e.slug = exp_slug
+      AND user_bucket(e.salt, variant_user_id)::BIGINT * v.total_weight < v.upper_weight * 10000
+    ORDER BY v.upper_weight
+    LIMIT 1;
+$$
+LANGUAGE SQL STABLE;
+
+-- Returns the SQLSTATE of the rule the assignment violates or NULL if the user can be assigned.
+-- EX001 - the user is already in another variant of the same experiment
+CREATE OR REPLACE FUNCTION assignment_violation(assigned_slug VARCHAR(100), assigned_user_id INTEGER)
+RETURNS TEXT AS
+$$
+DECLARE
+    seg RECORD;
+BEGIN
+    IF EXISTS (SELECT 1 FROM segments_to_users
+               WHERE segment_slug = assigned_slug AND user_id = assigned_user_id) THEN
+        RETURN NULL;
+    END IF;
+
+    SELECT slug, experiment_slug INTO seg FROM segments WHERE slug = assigned_slug;
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    IF seg.experiment_slug IS NOT NULL AND EXISTS (
+        SELECT 1 FROM segments_to_users su
+        JOIN segments s ON s.slug = su.segment_slug
+        WHERE su.user_id = assigned_user_id AND s.experiment_slug = seg.experiment_slug
+    ) THEN
+        RETURN 'EX001';
+    END IF;
+
+    RETURN NULL;
+END;
+$$
+LANGUAGE PLPGSQL;
+
+-- Trigger rejecting assignments that violate segment rules
+CREATE OR REPLACE FUNCTION guard_segment_assignment() RETURNS TRIGGER AS $$
+DECLARE
+    violation TEXT;
+BEGIN
+    violation := assignment_violation(NEW.segment_slug, NEW.user_id);
+    IF violation IS NOT NULL THEN
+        RAISE EXCEPTION 'user % can not be assigned segment %', NEW.user_id, NEW.segment_slug
+            USING ERRCODE = violation;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER segments_to_users_guard_trigger
+BEFORE INSERT ON segments_to_users
+FOR EACH ROW
+EXECUTE FUNCTION guard_segment_assignment();
+
+-- Enrolls the user into every percentage segment whose rule matches him
+-- and into one variant of every experiment.
+-- Deterministic segments compare the user bucket, the others add the user
+-- while the number of active members is below the configured share of all users
+CREATE OR REPLACE FUNCTION enroll_user_in_rollouts(enrolled_user_id INTEGER)
+RETURNS TABLE (segment_slug VARCHAR(100)) AS
+$$
+DECLARE
+    seg RECORD;
+    exp RECORD;
+    users_count INTEGER;
+    members_count INTEGER;
+BEGIN
+    users_count := (SELECT COUNT(*) FROM users);
+
+    FOR seg IN
+        SELECT slug, salt, rollout_percent, deterministic
+        FROM segments
+        WHERE rollout_percent IS NOT NULL
+        ORDER BY slug
+    LOOP
+        IF EXISTS (SELECT 1 FROM segments_to_users s
+                   WHERE s.segment_slug = seg.slug AND s.user_id = enrolled_user_id) THEN
+            CONTINUE;
+        END IF;
+
+        IF seg.deterministic THEN
+            IF user_bucket(seg.salt, enrolled_user_id) >= seg.rollout_percent * 100 THEN
+                CONTINUE;
+            END IF;
+        ELSE
+            members_count := (SELECT COUNT(*) FROM segments_to_users s
+                              WHERE s.segment_slug = seg.slug AND s.expiration_date > NOW());
+            IF members_count >= ROUND(users_count * (seg.rollout_percent / 100)) THEN
+                CONTINUE;
+            END IF;
+        END IF;
+
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (seg.slug, enrolled_user_id, 'INFINITY');
+
+        segment_slug := seg.slug;
+        RETURN NEXT;
+    END LOOP;
+
+    FOR exp IN
+        SELECT slug FROM experiments ORDER BY slug
+    LOOP
+        segment_slug := experiment_variant(exp.slug, enrolled_user_id);
+        IF segment_slug IS NULL OR assignment_violation(segment_slug, enrolled_user_id) IS NOT NULL THEN
+            CONTINUE;
+        END IF;
+
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (segment_slug, enrolled_user_id, 'INFINITY');
+
+        RETURN NEXT;
+    END LOOP;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
@@ -0,0 +1,216 @@
+-- A user can be an active member of at most one segment of a layer
+ALTER TABLE experiments ADD COLUMN IF NOT EXISTS layer VARCHAR(100);
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS layer VARCHAR(100);
+CREATE INDEX IF NOT EXISTS segments_layer_idx ON segments (layer);
+
+-- Returns the SQLSTATE of the rule the assignment violates or NULL if the user can be assigned.
+-- EX001 - the user is already in another variant of the same experiment
+-- EX002 - the user is already in another segment of the same layer
+CREATE OR REPLACE FUNCTION assignment_violation(assigned_slug VARCHAR(100), assigned_user_id INTEGER)
+RETURNS TEXT AS
+$$
+DECLARE
+    seg RECORD;
+BEGIN
+    IF EXISTS (SELECT 1 FROM segments_to_users
+               WHERE segment_slug = assigned_slug AND user_id = assigned_user_id) THEN
+        RETURN NULL;
+    END IF;
+
+    SELECT slug, experiment_slug, layer INTO seg FROM segments WHERE slug = assigned_slug;
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    IF seg.experiment_slug IS NOT NULL AND EXISTS (
+        SELECT 1 FROM segments_to_users su
+        JOIN segments s ON s.slug = su.segment_slug
+        WHERE su.user_id = assigned_user_id AND s.experiment_slug = seg.experiment_slug
+    ) THEN
+        RETURN 'EX001';
+    END IF;
+
+    IF seg.layer IS NOT NULL AND EXISTS (
+        SELECT 1 FROM segments_to_users su
+        JOIN segments s ON s.slug = su.segment_slug
+        WHERE su.user_id = assigned_user_id AND s.layer = seg.layer AND su.expiration_date > NOW()
+    ) THEN
+        RETURN 'EX002';
+    END IF;
+
+    RETURN NULL;
+END;
+$$
+LANGUAGE PLPGSQL;
+
+DROP FUNCTION IF EXISTS create_segment_and_add_users(VARCHAR, DECIMAL, BOOLEAN, VARCHAR);
+
+-- Function for automatically assigning segments to users.
+-- The percent is stored as a rollout rule, so users registered later are enrolled too.
+-- Users the segment can not be assigned to (e.g. already in its layer) are skipped
+CREATE OR REPLACE FUNCTION create_segment_and_add_users(new_slug VARCHAR(100), target_percent DECIMAL,
+    deterministic BOOLEAN, segment_salt VARCHAR(100), segment_layer VARCHAR(100))
+RETURNS TABLE (user_id INTEGER, segment_created BOOLEAN) AS
+$$
+DECLARE
+    users_to_add INTEGER;
+BEGIN
+    IF EXISTS (SELECT 1 FROM segments WHERE slug = new_slug) THEN
+        segment_created := FALSE;
+        RETURN QUERY SELECT -1, FALSE;
+    ELSE
+        INSERT INTO segments (slug, salt, rollout_percent, deterministic, layer)
+        VALUES (new_slug, segment_salt, target_percent, create_segment_and_add_users.deterministic, segment_layer);
+        segment_created := TRUE;
+    END IF;
+
+    IF segment_created = TRUE AND deterministic = TRUE THEN
+    FOR user_id IN
+        SELECT id
+        FROM users
+        WHERE user_bucket(segment_salt, id) < target_percent * 100
+        ORDER BY id
+    LOOP
+        IF assignment_violation(new_slug, user_id) IS NOT NULL THEN
+            CONTINUE;
+        END IF;
+
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (new_slug, user_id, 'INFINITY');
+
+        RETURN NEXT;
+    END LOOP;
+    ELSIF segment_created = TRUE THEN
+    users_to_add := ROUND((SELECT COUNT(*) FROM users) * (target_percent / 100));
+    FOR user_id IN
+        SELECT id
+        FROM users
+        WHERE id NOT IN (SELECT segments_to_users.user_id FROM segments_to_users WHERE segment_slug = new_slug)
+          AND assignment_violation(new_slug, id) IS NULL
+        ORDER BY random()
+        LIMIT users_to_add
+    LOOP
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (new_slug, user_id, 'INFINITY');
+
+        RETURN NEXT;
+    END LOOP;
+    END IF;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
+
+-- Enrolls the user into every percentage segment whose rule matches him
+-- and into one variant of every experiment.
+-- Deterministic segments compare the user bucket, the others add the user
+-- while the number of active members is below the configured share of all users
+CREATE OR REPLACE FUNCTION enroll_user_in_rollouts(enrolled_user_id INTEGER)
+RETURNS TABLE (segment_slug VARCHAR(100)) AS
+$$
+DECLARE
+    seg RECORD;
+    exp RECORD;
+    users_count INTEGER;
+    members_count INTEGER;
+BEGIN
+    users_count := (SELECT COUNT(*) FROM users);
+
+    FOR seg IN
+        SELECT slug, salt, rollout_percent, deterministic
+        FROM segments
+        WHERE rollout_percent IS NOT NULL
+        ORDER BY slug
+    LOOP
+        IF EXISTS (SELECT 1 FROM segments_to_users s
+                   WHERE s.segment_slug = seg.slug AND s.user_id = enrolled_user_id) THEN
+            CONTINUE;
+        END IF;
+
+        IF seg.deterministic THEN
+            IF user_bucket(seg.salt, enrolled_user_id) >= seg.rollout_percent * 100 THEN
+                CONTINUE;
+            END IF;
+        ELSE
+            members_count := (SELECT COUNT(*) FROM segments_to_users s
+                              WHERE s.segment_slug = seg.slug AND s.expiration_date > NOW());
+            IF members_count >= ROUND(users_count * (seg.rollout_percent / 100)) THEN
+                CONTINUE;
+            END IF;
+        END IF;
+
+        IF assignment_violation(seg.slug, enrolled_user_id) IS NOT NULL THEN
+            CONTINUE;
+        END IF;
+
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (seg.slug, enrolled_user_id, 'INFINITY');
+
+        segment_slug := seg.slug;
+        RETURN NEXT;
+    END LOOP;
+
+    FOR exp IN
+        SELECT slug FROM experiments ORDER BY slug
+    LOOP
+        segment_slug := experiment_variant(exp.slug, enrolled_user_id);
+        IF segment_slug IS NULL OR assignment_violation(segment_slug, enrolled_user_id) IS NOT NULL THEN
+            CONTINUE;
+        END IF;
+
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (segment_slug, enrolled_user_id, 'INFINITY');
+
+        RETURN NEXT;
+    END LOOP;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
+
+-- Evaluates percentage segments for all users, so the real share gets back to the configured percent
+CREATE OR REPLACE FUNCTION backfill_rollouts()
+RETURNS TABLE (segment_slug VARCHAR(100), user_id INTEGER) AS
+$$
+DECLARE
+    seg RECORD;
+    users_to_add INTEGER;
+BEGIN
+    FOR seg IN
+        SELECT slug, salt, rollout_percent, deterministic
+        FROM segments
+        WHERE rollout_percent IS NOT NULL
+        ORDER BY slug
+    LOOP
+        segment_slug := seg.slug;
+
+        IF seg.deterministic THEN
+            users_to_add := NULL;
+        ELSE
+            users_to_add := GREATEST(ROUND((SELECT COUNT(*) FROM users) * (seg.rollout_percent / 100))
+                - (SELECT COUNT(*) FROM segments_to_users s
+                   WHERE s.segment_slug = seg.slug AND s.expiration_date > NOW()), 0);
+        END IF;
+
+        FOR user_id IN
+            SELECT id
+            FROM users
+            WHERE id NOT IN (SELECT s.user_id FROM segments_to_users s WHERE s.segment_slug = seg.slug)
+              AND (NOT seg.deterministic OR user_bucket(seg.salt, id) < seg.rollout_percent * 100)
+              AND assignment_violation(seg.slug, id) IS NULL
+            ORDER BY CASE WHEN seg.deterministic THEN id END, random()
+            LIMIT users_to_add
+        LOOP
+            INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+            VALUES (seg.slug, user_id, 'INFINITY');
+
+            RETURN NEXT;
+        END LOOP;
+    END LOOP;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
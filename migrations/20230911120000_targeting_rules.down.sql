@@ -0,0 +1,3 @@
+ALTER TABLE segments DROP COLUMN IF EXISTS targeting_rule;
+ALTER TABLE users DROP COLUMN IF EXISTS created_at;
+ALTER TABLE users DROP COLUMN IF EXISTS attributes;
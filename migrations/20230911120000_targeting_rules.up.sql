@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW();
+
+-- Users whose attributes match the rule are members of the segment, see pkg/rules
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS targeting_rule TEXT;
@@ -0,0 +1,67 @@
+DROP FUNCTION IF EXISTS create_segment_and_add_users(VARCHAR, DECIMAL, BOOLEAN, VARCHAR, VARCHAR, TEXT, VARCHAR, TEXT[]);
+
+-- Function for automatically assigning segments to users.
+-- The percent is stored as a rollout rule, so users registered later are enrolled too.
+-- Users the segment can not be assigned to (e.g. already in its layer) are skipped
+CREATE OR REPLACE FUNCTION create_segment_and_add_users(new_slug VARCHAR(100), target_percent DECIMAL,
+    deterministic BOOLEAN, segment_salt VARCHAR(100), segment_layer VARCHAR(100))
+RETURNS TABLE (user_id INTEGER, segment_created BOOLEAN) AS
+$$
+DECLARE
+    users_to_add INTEGER;
+BEGIN
+    IF EXISTS (SELECT 1 FROM segments WHERE slug = new_slug) THEN
+        segment_created := FALSE;
+        RETURN QUERY SELECT -1, FALSE;
+    ELSE
+        INSERT INTO segments (slug, salt, rollout_percent, deterministic, layer)
+        VALUES (new_slug, segment_salt, target_percent, create_segment_and_add_users.deterministic, segment_layer);
+        segment_created := TRUE;
+    END IF;
+
+    IF segment_created = TRUE AND deterministic = TRUE THEN
+    FOR user_id IN
+        SELECT id
+        FROM users
+        WHERE user_bucket(segment_salt, id) < target_percent * 100
+        ORDER BY id
+    LOOP
+        IF assignment_violation(new_slug, user_id) IS NOT NULL THEN
+            CONTINUE;
+        END IF;
+
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (new_slug, user_id, 'INFINITY');
+
+        RETURN NEXT;
+    END LOOP;
+    ELSIF segment_created = TRUE THEN
+    users_to_add := ROUND((SELECT COUNT(*) FROM users) * (target_percent / 100));
+    FOR user_id IN
+        SELECT id
+        FROM users
+        WHERE id NOT IN (SELECT segments_to_users.user_id FROM segments_to_users WHERE segment_slug = new_slug)
+          AND assignment_violation(new_slug, id) IS NULL
+        ORDER BY random()
+        LIMIT users_to_add
+    LOOP
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (new_slug, user_id, 'INFINITY');
+
+        RETURN NEXT;
+    END LOOP;
+    END IF;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
+
+DROP TRIGGER IF EXISTS segments_audit_trigger ON segments;
+DROP FUNCTION IF EXISTS audit_segment_changes();
+DROP TABLE IF EXISTS segment_changes;
+ALTER TABLE segments DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE segments DROP COLUMN IF EXISTS created_at;
+ALTER TABLE segments DROP COLUMN IF EXISTS tags;
+ALTER TABLE segments DROP COLUMN IF EXISTS owner;
+ALTER TABLE segments DROP COLUMN IF EXISTS description;
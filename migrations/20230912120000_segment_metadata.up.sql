@@ -0,0 +1,99 @@
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS owner VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW();
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW();
+
+CREATE TABLE IF NOT EXISTS segment_changes (
+    change_id SERIAL PRIMARY KEY,
+    segment_slug VARCHAR(100) NOT NULL,
+    old_values JSONB NOT NULL,
+    new_values JSONB NOT NULL,
+    change_date TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS segment_changes_slug_idx ON segment_changes (segment_slug);
+
+-- Trigger for populating the segment_changes table with the changed columns only
+CREATE OR REPLACE FUNCTION audit_segment_changes() RETURNS TRIGGER AS $$
+DECLARE
+    old_row JSONB := to_jsonb(OLD) - 'updated_at';
+    new_row JSONB := to_jsonb(NEW) - 'updated_at';
+BEGIN
+    IF old_row = new_row THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO segment_changes (segment_slug, old_values, new_values, change_date)
+    SELECT NEW.slug, jsonb_object_agg(k, old_row -> k), jsonb_object_agg(k, new_row -> k), CURRENT_TIMESTAMP
+    FROM jsonb_object_keys(new_row) k
+    WHERE old_row -> k IS DISTINCT FROM new_row -> k;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER segments_audit_trigger
+AFTER UPDATE ON segments
+FOR EACH ROW
+EXECUTE FUNCTION audit_segment_changes();
+
+DROP FUNCTION IF EXISTS create_segment_and_add_users(VARCHAR, DECIMAL, BOOLEAN, VARCHAR, VARCHAR);
+
+-- Function for automatically assigning segments to users.
+-- The percent is stored as a rollout rule, so users registered later are enrolled too.
+-- Users the segment can not be assigned to (e.g. already in its layer) are skipped
+CREATE OR REPLACE FUNCTION create_segment_and_add_users(new_slug VARCHAR(100), target_percent DECIMAL,
+    deterministic BOOLEAN, segment_salt VARCHAR(100), segment_layer VARCHAR(100),
+    segment_description TEXT, segment_owner VARCHAR(100), segment_tags TEXT[])
+RETURNS TABLE (user_id INTEGER, segment_created BOOLEAN) AS
+$$
+DECLARE
+    users_to_add INTEGER;
+BEGIN
+    IF EXISTS (SELECT 1 FROM segments WHERE slug = new_slug) THEN
+        segment_created := FALSE;
+        RETURN QUERY SELECT -1, FALSE;
+    ELSE
+        INSERT INTO segments (slug, salt, rollout_percent, deterministic, layer, description, owner, tags)
+        VALUES (new_slug, segment_salt, target_percent, create_segment_and_add_users.deterministic, segment_layer,
+            segment_description, segment_owner, segment_tags);
+        segment_created := TRUE;
+    END IF;
+
+    IF segment_created = TRUE AND deterministic = TRUE THEN
+    FOR user_id IN
+        SELECT id
+        FROM users
+        WHERE user_bucket(segment_salt, id) < target_percent * 100
+        ORDER BY id
+    LOOP
+        IF assignment_violation(new_slug, user_id) IS NOT NULL THEN
+            CONTINUE;
+        END IF;
+
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (new_slug, user_id, 'INFINITY');
+
+        RETURN NEXT;
+    END LOOP;
+    ELSIF segment_created = TRUE THEN
+    users_to_add := ROUND((SELECT COUNT(*) FROM users) * (target_percent / 100));
+    FOR user_id IN
+        SELECT id
+        FROM users
+        WHERE id NOT IN (SELECT segments_to_users.user_id FROM segments_to_users WHERE segment_slug = new_slug)
+          AND assignment_violation(new_slug, id) IS NULL
+        ORDER BY random()
+        LIMIT users_to_add
+    LOOP
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (new_slug, user_id, 'INFINITY');
+
+        RETURN NEXT;
+    END LOOP;
+    END IF;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
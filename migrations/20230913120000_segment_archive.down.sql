@@ -0,0 +1,41 @@
+-- Returns the SQLSTATE of the rule the assignment violates or NULL if the user can be assigned.
+-- EX001 - the user is already in another variant of the same experiment
+-- EX002 - the user is already in another segment of the same layer
+CREATE OR REPLACE FUNCTION assignment_violation(assigned_slug VARCHAR(100), assigned_user_id INTEGER)
+RETURNS TEXT AS
+$$
+DECLARE
+    seg RECORD;
+BEGIN
+    IF EXISTS (SELECT 1 FROM segments_to_users
+               WHERE segment_slug = assigned_slug AND user_id = assigned_user_id) THEN
+        RETURN NULL;
+    END IF;
+
+    SELECT slug, experiment_slug, layer INTO seg FROM segments WHERE slug = assigned_slug;
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    IF seg.experiment_slug IS NOT NULL AND EXISTS (
+        SELECT 1 FROM segments_to_users su
+        JOIN segments s ON s.slug = su.segment_slug
+        WHERE su.user_id = assigned_user_id AND s.experiment_slug = seg.experiment_slug
+    ) THEN
+        RETURN 'EX001';
+    END IF;
+
+    IF seg.layer IS NOT NULL AND EXISTS (
+        SELECT 1 FROM segments_to_users su
+        JOIN segments s ON s.slug = su.segment_slug
+        WHERE su.user_id = assigned_user_id AND s.layer = seg.layer AND su.expiration_date > NOW()
+    ) THEN
+        RETURN 'EX002';
+    END IF;
+
+    RETURN NULL;
+END;
+$$
+LANGUAGE PLPGSQL;
+
+ALTER TABLE segments DROP COLUMN IF EXISTS archived_at;
@@ -0,0 +1,3 @@
+ALTER TABLE segments DROP CONSTRAINT IF EXISTS segments_window_check;
+ALTER TABLE segments DROP COLUMN IF EXISTS ends_at;
+ALTER TABLE segments DROP COLUMN IF EXISTS starts_at;
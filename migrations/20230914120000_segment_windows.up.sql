@@ -0,0 +1,4 @@
+-- Segments are returned to users only while now is within [starts_at, ends_at)
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS starts_at TIMESTAMP WITHOUT TIME ZONE;
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS ends_at TIMESTAMP WITHOUT TIME ZONE;
+ALTER TABLE segments ADD CONSTRAINT segments_window_check CHECK (starts_at < ends_at);
@@ -0,0 +1,2 @@
+DROP FUNCTION IF EXISTS set_segment_rollout(VARCHAR, DECIMAL);
+ALTER TABLE segments_to_users DROP COLUMN IF EXISTS assigned_at;
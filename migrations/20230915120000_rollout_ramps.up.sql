@@ -0,0 +1,99 @@
+-- Time of joining the segment, used to remove the most recently added members first
+ALTER TABLE segments_to_users ADD COLUMN IF NOT EXISTS assigned_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT NOW();
+
+UPDATE segments_to_users su SET assigned_at = o.operation_date
+FROM (
+    SELECT user_id, segment_slug, MAX(operation_date) AS operation_date
+    FROM segment_user_operations
+    WHERE isAdded
+    GROUP BY user_id, segment_slug
+) o
+WHERE o.user_id = su.user_id AND o.segment_slug = su.segment_slug;
+
+-- Function for raising or lowering the rollout percent of a segment.
+-- Increasing keeps the members and adds new users, decreasing removes members:
+-- by bucket for deterministic segments, the most recently added first otherwise.
+-- Returns the added and removed users
+CREATE OR REPLACE FUNCTION set_segment_rollout(target_slug VARCHAR(100), target_percent DECIMAL)
+RETURNS TABLE (user_id INTEGER, added BOOLEAN) AS
+$$
+DECLARE
+    seg RECORD;
+    target_count INTEGER;
+    members_count INTEGER;
+BEGIN
+    SELECT slug, salt, deterministic INTO seg FROM segments WHERE slug = target_slug FOR UPDATE;
+    IF NOT FOUND THEN
+        RETURN;
+    END IF;
+
+    UPDATE segments SET rollout_percent = target_percent, updated_at = NOW() WHERE slug = target_slug;
+
+    IF seg.deterministic THEN
+        added := FALSE;
+        FOR user_id IN
+            DELETE FROM segments_to_users su
+            WHERE su.segment_slug = target_slug AND su.expiration_date > NOW()
+              AND user_bucket(seg.salt, su.user_id) >= target_percent * 100
+            RETURNING su.user_id
+        LOOP
+            RETURN NEXT;
+        END LOOP;
+
+        added := TRUE;
+        FOR user_id IN
+            SELECT id
+            FROM users
+            WHERE user_bucket(seg.salt, id) < target_percent * 100
+              AND id NOT IN (SELECT su.user_id FROM segments_to_users su WHERE su.segment_slug = target_slug)
+              AND assignment_violation(target_slug, id) IS NULL
+            ORDER BY id
+        LOOP
+            INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+            VALUES (target_slug, user_id, 'INFINITY');
+
+            RETURN NEXT;
+        END LOOP;
+
+        RETURN;
+    END IF;
+
+    target_count := ROUND((SELECT COUNT(*) FROM users) * (target_percent / 100));
+    members_count := (SELECT COUNT(*) FROM segments_to_users su
+                      WHERE su.segment_slug = target_slug AND su.expiration_date > NOW());
+
+    IF members_count > target_count THEN
+        added := FALSE;
+        FOR user_id IN
+            DELETE FROM segments_to_users su
+            WHERE su.segment_slug = target_slug AND su.user_id IN (
+                SELECT m.user_id FROM segments_to_users m
+                WHERE m.segment_slug = target_slug AND m.expiration_date > NOW()
+                ORDER BY m.assigned_at DESC, m.user_id DESC
+                LIMIT members_count - target_count
+            )
+            RETURNING su.user_id
+        LOOP
+            RETURN NEXT;
+        END LOOP;
+    ELSIF members_count < target_count THEN
+        added := TRUE;
+        FOR user_id IN
+            SELECT id
+            FROM users
+            WHERE id NOT IN (SELECT su.user_id FROM segments_to_users su WHERE su.segment_slug = target_slug)
+              AND assignment_violation(target_slug, id) IS NULL
+            ORDER BY random()
+            LIMIT target_count - members_count
+        LOOP
+            INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+            VALUES (target_slug, user_id, 'INFINITY');
+
+            RETURN NEXT;
+        END LOOP;
+    END IF;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
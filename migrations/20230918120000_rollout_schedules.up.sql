@@ -0,0 +1,11 @@
+-- Planned changes of segment rollout percent, applied by the in-process scheduler.
+-- applied_at is set in the same transaction as the rollout change, so a step is never applied twice
+CREATE TABLE IF NOT EXISTS rollout_steps (
+    step_id SERIAL PRIMARY KEY,
+    segment_slug VARCHAR(100) REFERENCES segments(slug) ON DELETE CASCADE NOT NULL,
+    percent DECIMAL NOT NULL CHECK (percent >= 0 AND percent <= 100),
+    apply_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    applied_at TIMESTAMP WITHOUT TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS rollout_steps_segment_idx ON rollout_steps (segment_slug);
+CREATE INDEX IF NOT EXISTS rollout_steps_pending_idx ON rollout_steps (apply_at) WHERE applied_at IS NULL;
@@ -0,0 +1,90 @@
+-- Global holdout: users whose bucket of (salt, user id) is below percent * 100
+-- are never enrolled in segments unless the segment explicitly allows it.
+-- The table holds at most one row, no row means there is no holdout
+CREATE TABLE IF NOT EXISTS holdout (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    salt VARCHAR(100) NOT NULL,
+    percent DECIMAL NOT NULL CHECK (percent >= 0 AND percent <= 100)
+);
+
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS allow_holdout BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE OR REPLACE FUNCTION in_holdout(holdout_user_id INTEGER)
+RETURNS BOOLEAN AS
+$$
+    SELECT EXISTS (SELECT 1 FROM holdout WHERE user_bucket(salt, holdout_user_id) < percent * 100);
+$$
+LANGUAGE SQL STABLE;
+
+-- Returns the SQLSTATE of the rule the assignment violates or NULL if the user can be assigned.
+-- EX001 - the user is already in another variant of the same experiment
+-- EX002 - the user is already in another segment of the same layer
+-- EX003 - the segment is archived
+-- EX004 - the user is in the holdout and the segment does not allow it
+CREATE OR REPLACE FUNCTION assignment_violation(assigned_slug VARCHAR(100), assigned_user_id INTEGER)
+RETURNS TEXT AS
+$$
+DECLARE
+    seg RECORD;
+BEGIN
+    IF EXISTS (SELECT 1 FROM segments_to_users
+               WHERE segment_slug = assigned_slug AND user_id = assigned_user_id) THEN
+        RETURN NULL;
+    END IF;
+
+    SELECT slug, experiment_slug, layer, archived_at, allow_holdout INTO seg FROM segments WHERE slug = assigned_slug;
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    IF seg.archived_at IS NOT NULL THEN
+        RETURN 'EX003';
+    END IF;
+
+    IF NOT seg.allow_holdout AND in_holdout(assigned_user_id) THEN
+        RETURN 'EX004';
+    END IF;
+
+    IF seg.experiment_slug IS NOT NULL AND EXISTS (
+        SELECT 1 FROM segments_to_users su
+        JOIN segments s ON s.slug = su.segment_slug
+        WHERE su.user_id = assigned_user_id AND s.experiment_slug = seg.experiment_slug
+    ) THEN
+        RETURN 'EX001';
+    END IF;
+
+    IF seg.layer IS NOT NULL AND EXISTS (
+        SELECT 1 FROM segments_to_users su
+        JOIN segments s ON s.slug = su.segment_slug
+        WHERE su.user_id = assigned_user_id AND s.layer = seg.layer AND su.expiration_date > NOW()
+    ) THEN
+        RETURN 'EX002';
+    END IF;
+
+    RETURN NULL;
+END;
+$$
+LANGUAGE PLPGSQL;
+
+-- Auto-assigned segments are inserted with their settings first and then filled by set_segment_rollout,
+-- so every segment column is known before users are picked. The function is kept for existing callers
+-- and delegates to set_segment_rollout, so holdout users are skipped as well
+CREATE OR REPLACE FUNCTION create_segment_and_add_users(new_slug VARCHAR(100), target_percent DECIMAL,
+    deterministic BOOLEAN, segment_salt VARCHAR(100), segment_layer VARCHAR(100),
+    segment_description TEXT, segment_owner VARCHAR(100), segment_tags TEXT[])
+RETURNS TABLE (user_id INTEGER, segment_created BOOLEAN) AS
+$$
+BEGIN
+    IF EXISTS (SELECT 1 FROM segments WHERE slug = new_slug) THEN
+        RETURN QUERY SELECT -1, FALSE;
+        RETURN;
+    END IF;
+
+    INSERT INTO segments (slug, salt, rollout_percent, deterministic, layer, description, owner, tags)
+    VALUES (new_slug, segment_salt, target_percent, create_segment_and_add_users.deterministic, segment_layer,
+        segment_description, segment_owner, segment_tags);
+
+    RETURN QUERY SELECT r.user_id, TRUE FROM set_segment_rollout(new_slug, target_percent) r WHERE r.added;
+END;
+$$
+LANGUAGE PLPGSQL;
@@ -0,0 +1,227 @@
+-- Maximum number of active members, NULL means the segment is unlimited
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS max_members INTEGER CHECK (max_members > 0);
+
+-- Returns the SQLSTATE of the rule the assignment violates or NULL if the user can be assigned.
+-- EX001 - the user is already in another variant of the same experiment
+-- EX002 - the user is already in another segment of the same layer
+-- EX003 - the segment is archived
+-- EX004 - the user is in the holdout and the segment does not allow it
+-- EX005 - the user is not an active member of a prerequisite of the segment
+-- EX006 - the segment already has max_members active members.
+-- The capacity is checked under the segment row lock held until the end of the transaction,
+-- so concurrent assignments to a limited segment are serialized
+CREATE OR REPLACE FUNCTION assignment_violation(assigned_slug VARCHAR(100), assigned_user_id INTEGER)
+RETURNS TEXT AS
+$$
+DECLARE
+    seg RECORD;
+BEGIN
+    IF EXISTS (SELECT 1 FROM segments_to_users
+               WHERE segment_slug = assigned_slug AND user_id = assigned_user_id) THEN
+        RETURN NULL;
+    END IF;
+
+    SELECT slug, experiment_slug, layer, archived_at, allow_holdout, max_members INTO seg FROM segments WHERE slug = assigned_slug;
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    IF seg.archived_at IS NOT NULL THEN
+        RETURN 'EX003';
+    END IF;
+
+    IF NOT seg.allow_holdout AND in_holdout(assigned_user_id) THEN
+        RETURN 'EX004';
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM segment_prerequisites sp
+        WHERE sp.segment_slug = assigned_slug AND NOT EXISTS (
+            SELECT 1 FROM segments_to_users su
+            WHERE su.segment_slug = sp.prerequisite_slug AND su.user_id = assigned_user_id
+              AND su.expiration_date > NOW()
+        )
+    ) THEN
+        RETURN 'EX005';
+    END IF;
+
+    IF seg.experiment_slug IS NOT NULL AND EXISTS (
+        SELECT 1 FROM segments_to_users su
+        JOIN segments s ON s.slug = su.segment_slug
+        WHERE su.user_id = assigned_user_id AND s.experiment_slug = seg.experiment_slug
+    ) THEN
+        RETURN 'EX001';
+    END IF;
+
+    IF seg.layer IS NOT NULL AND EXISTS (
+        SELECT 1 FROM segments_to_users su
+        JOIN segments s ON s.slug = su.segment_slug
+        WHERE su.user_id = assigned_user_id AND s.layer = seg.layer AND su.expiration_date > NOW()
+    ) THEN
+        RETURN 'EX002';
+    END IF;
+
+    IF seg.max_members IS NOT NULL THEN
+        PERFORM 1 FROM segments WHERE slug = assigned_slug FOR NO KEY UPDATE;
+        IF (SELECT COUNT(*) FROM segments_to_users su
+            WHERE su.segment_slug = assigned_slug AND su.expiration_date > NOW()) >= seg.max_members THEN
+            RETURN 'EX006';
+        END IF;
+    END IF;
+
+    RETURN NULL;
+END;
+$$
+LANGUAGE PLPGSQL;
+
+-- Function for raising or lowering the rollout percent of a segment.
+-- Increasing keeps the members and adds new users, decreasing removes members:
+-- by bucket for deterministic segments, the most recently added first otherwise.
+-- Members are added only while the segment has free capacity.
+-- Returns the added and removed users
+CREATE OR REPLACE FUNCTION set_segment_rollout(target_slug VARCHAR(100), target_percent DECIMAL)
+RETURNS TABLE (user_id INTEGER, added BOOLEAN) AS
+$$
+DECLARE
+    seg RECORD;
+    target_count INTEGER;
+    members_count INTEGER;
+    capacity INTEGER;
+BEGIN
+    SELECT slug, salt, deterministic, max_members INTO seg FROM segments WHERE slug = target_slug FOR UPDATE;
+    IF NOT FOUND THEN
+        RETURN;
+    END IF;
+
+    UPDATE segments SET rollout_percent = target_percent, updated_at = NOW() WHERE slug = target_slug;
+
+    IF seg.deterministic THEN
+        added := FALSE;
+        FOR user_id IN
+            DELETE FROM segments_to_users su
+            WHERE su.segment_slug = target_slug AND su.expiration_date > NOW()
+              AND user_bucket(seg.salt, su.user_id) >= target_percent * 100
+            RETURNING su.user_id
+        LOOP
+            RETURN NEXT;
+        END LOOP;
+
+        IF seg.max_members IS NOT NULL THEN
+            capacity := GREATEST(seg.max_members - (SELECT COUNT(*) FROM segments_to_users su
+                                                    WHERE su.segment_slug = target_slug AND su.expiration_date > NOW()), 0);
+        END IF;
+
+        added := TRUE;
+        FOR user_id IN
+            SELECT id
+            FROM users
+            WHERE user_bucket(seg.salt, id) < target_percent * 100
+              AND id NOT IN (SELECT su.user_id FROM segments_to_users su WHERE su.segment_slug = target_slug)
+              AND assignment_violation(target_slug, id) IS NULL
+            ORDER BY id
+            LIMIT capacity
+        LOOP
+            INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+            VALUES (target_slug, user_id, 'INFINITY');
+
+            RETURN NEXT;
+        END LOOP;
+
+        RETURN;
+    END IF;
+
+    target_count := ROUND((SELECT COUNT(*) FROM users) * (target_percent / 100));
+    members_count := (SELECT COUNT(*) FROM segments_to_users su
+                      WHERE su.segment_slug = target_slug AND su.expiration_date > NOW());
+    capacity := GREATEST(seg.max_members - members_count, 0);
+
+    IF members_count > target_count THEN
+        added := FALSE;
+        FOR user_id IN
+            DELETE FROM segments_to_users su
+            WHERE su.segment_slug = target_slug AND su.user_id IN (
+                SELECT m.user_id FROM segments_to_users m
+                WHERE m.segment_slug = target_slug AND m.expiration_date > NOW()
+                ORDER BY m.assigned_at DESC, m.user_id DESC
+                LIMIT members_count - target_count
+            )
+            RETURNING su.user_id
+        LOOP
+            RETURN NEXT;
+        END LOOP;
+    ELSIF members_count < target_count THEN
+        added := TRUE;
+        FOR user_id IN
+            SELECT id
+            FROM users
+            WHERE id NOT IN (SELECT su.user_id FROM segments_to_users su WHERE su.segment_slug = target_slug)
+              AND assignment_violation(target_slug, id) IS NULL
+            ORDER BY random()
+            LIMIT LEAST(target_count - members_count, capacity)
+        LOOP
+            INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+            VALUES (target_slug, user_id, 'INFINITY');
+
+            RETURN NEXT;
+        END LOOP;
+    END IF;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
+
+-- Evaluates percentage segments for all users, so the real share gets back to the configured percent.
+-- Segments with max_members are filled only up to their capacity
+CREATE OR REPLACE FUNCTION backfill_rollouts()
+RETURNS TABLE (segment_slug VARCHAR(100), user_id INTEGER) AS
+$$
+DECLARE
+    seg RECORD;
+    users_to_add INTEGER;
+    members_count INTEGER;
+BEGIN
+    FOR seg IN
+        SELECT slug, salt, rollout_percent, deterministic, max_members
+        FROM segments
+        WHERE rollout_percent IS NOT NULL
+        ORDER BY slug
+    LOOP
+        segment_slug := seg.slug;
+
+        IF seg.max_members IS NOT NULL THEN
+            PERFORM 1 FROM segments WHERE slug = seg.slug FOR NO KEY UPDATE;
+        END IF;
+        members_count := (SELECT COUNT(*) FROM segments_to_users s
+                          WHERE s.segment_slug = seg.slug AND s.expiration_date > NOW());
+
+        IF seg.deterministic THEN
+            users_to_add := NULL;
+        ELSE
+            users_to_add := GREATEST(ROUND((SELECT COUNT(*) FROM users) * (seg.rollout_percent / 100))
+                - members_count, 0);
+        END IF;
+        IF seg.max_members IS NOT NULL THEN
+            users_to_add := LEAST(users_to_add, GREATEST(seg.max_members - members_count, 0));
+        END IF;
+
+        FOR user_id IN
+            SELECT id
+            FROM users
+            WHERE id NOT IN (SELECT s.user_id FROM segments_to_users s WHERE s.segment_slug = seg.slug)
+              AND (NOT seg.deterministic OR user_bucket(seg.salt, id) < seg.rollout_percent * 100)
+              AND assignment_violation(seg.slug, id) IS NULL
+            ORDER BY CASE WHEN seg.deterministic THEN id END, random()
+            LIMIT users_to_add
+        LOOP
+            INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+            VALUES (seg.slug, user_id, 'INFINITY');
+
+            RETURN NEXT;
+        END LOOP;
+    END LOOP;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
@@ -0,0 +1 @@
+DROP FUNCTION IF EXISTS add_random_users(VARCHAR(100), DECIMAL, TIMESTAMP WITHOUT TIME ZONE);
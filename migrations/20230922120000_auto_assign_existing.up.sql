@@ -0,0 +1,45 @@
+-- Function for adding a share of the users that are not in the segment yet, with an optional expiration.
+-- The number of users is rounded like on segment creation, users the segment can not be assigned to
+-- are skipped and the segment is filled only up to max_members. Returns the added users
+CREATE OR REPLACE FUNCTION add_random_users(target_slug VARCHAR(100), target_percent DECIMAL,
+    expires TIMESTAMP WITHOUT TIME ZONE)
+RETURNS TABLE (user_id INTEGER) AS
+$$
+DECLARE
+    seg RECORD;
+    users_to_add INTEGER;
+BEGIN
+    SELECT slug, max_members INTO seg FROM segments WHERE slug = target_slug FOR UPDATE;
+    IF NOT FOUND THEN
+        RETURN;
+    END IF;
+
+    users_to_add := ROUND((SELECT COUNT(*) FROM users u
+                           WHERE NOT EXISTS (SELECT 1 FROM segments_to_users su
+                                             WHERE su.segment_slug = target_slug AND su.user_id = u.id))
+                          * (target_percent / 100));
+    IF seg.max_members IS NOT NULL THEN
+        users_to_add := LEAST(users_to_add, GREATEST(seg.max_members
+            - (SELECT COUNT(*) FROM segments_to_users su
+               WHERE su.segment_slug = target_slug AND su.expiration_date > NOW()), 0));
+    END IF;
+
+    FOR user_id IN
+        SELECT u.id
+        FROM users u
+        WHERE NOT EXISTS (SELECT 1 FROM segments_to_users su
+                          WHERE su.segment_slug = target_slug AND su.user_id = u.id)
+          AND assignment_violation(target_slug, u.id) IS NULL
+        ORDER BY random()
+        LIMIT users_to_add
+    LOOP
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (target_slug, user_id, COALESCE(expires, 'INFINITY'));
+
+        RETURN NEXT;
+    END LOOP;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
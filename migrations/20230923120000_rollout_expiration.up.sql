@@ -0,0 +1,226 @@
+-- Expiration of the memberships added by the rollout, NULL means they never expire.
+-- Users are not enrolled into the segment once it has passed
+ALTER TABLE segments ADD COLUMN IF NOT EXISTS rollout_expires_at TIMESTAMP WITHOUT TIME ZONE;
+
+-- Enrolls the user into every percentage segment whose rule matches him
+-- and into one variant of every experiment.
+-- Deterministic segments compare the user bucket, the others add the user
+-- while the number of active members is below the configured share of all users.
+-- Memberships in percentage segments expire at the rollout expiration of the segment
+CREATE OR REPLACE FUNCTION enroll_user_in_rollouts(enrolled_user_id INTEGER)
+RETURNS TABLE (segment_slug VARCHAR(100)) AS
+$$
+DECLARE
+    seg RECORD;
+    exp RECORD;
+    users_count INTEGER;
+    members_count INTEGER;
+BEGIN
+    users_count := (SELECT COUNT(*) FROM users);
+
+    FOR seg IN
+        SELECT slug, salt, rollout_percent, deterministic, rollout_expires_at
+        FROM segments
+        WHERE rollout_percent IS NOT NULL AND (rollout_expires_at IS NULL OR rollout_expires_at > NOW())
+        ORDER BY slug
+    LOOP
+        IF EXISTS (SELECT 1 FROM segments_to_users s
+                   WHERE s.segment_slug = seg.slug AND s.user_id = enrolled_user_id) THEN
+            CONTINUE;
+        END IF;
+
+        IF seg.deterministic THEN
+            IF user_bucket(seg.salt, enrolled_user_id) >= seg.rollout_percent * 100 THEN
+                CONTINUE;
+            END IF;
+        ELSE
+            members_count := (SELECT COUNT(*) FROM segments_to_users s
+                              WHERE s.segment_slug = seg.slug AND s.expiration_date > NOW());
+            IF members_count >= ROUND(users_count * (seg.rollout_percent / 100)) THEN
+                CONTINUE;
+            END IF;
+        END IF;
+
+        IF assignment_violation(seg.slug, enrolled_user_id) IS NOT NULL THEN
+            CONTINUE;
+        END IF;
+
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (seg.slug, enrolled_user_id, COALESCE(seg.rollout_expires_at, 'INFINITY'));
+
+        segment_slug := seg.slug;
+        RETURN NEXT;
+    END LOOP;
+
+    FOR exp IN
+        SELECT slug FROM experiments ORDER BY slug
+    LOOP
+        segment_slug := experiment_variant(exp.slug, enrolled_user_id);
+        IF segment_slug IS NULL OR assignment_violation(segment_slug, enrolled_user_id) IS NOT NULL THEN
+            CONTINUE;
+        END IF;
+
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (segment_slug, enrolled_user_id, 'INFINITY');
+
+        RETURN NEXT;
+    END LOOP;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
+
+-- Function for raising or lowering the rollout percent of a segment.
+-- Increasing keeps the members and adds new users, decreasing removes members:
+-- by bucket for deterministic segments, the most recently added first otherwise.
+-- Members are added only while the segment has free capacity and expire at its rollout expiration.
+-- Returns the added and removed users
+CREATE OR REPLACE FUNCTION set_segment_rollout(target_slug VARCHAR(100), target_percent DECIMAL)
+RETURNS TABLE (user_id INTEGER, added BOOLEAN) AS
+$$
+DECLARE
+    seg RECORD;
+    target_count INTEGER;
+    members_count INTEGER;
+    capacity INTEGER;
+BEGIN
+    SELECT slug, salt, deterministic, max_members, rollout_expires_at INTO seg FROM segments WHERE slug = target_slug FOR UPDATE;
+    IF NOT FOUND THEN
+        RETURN;
+    END IF;
+
+    UPDATE segments SET rollout_percent = target_percent, updated_at = NOW() WHERE slug = target_slug;
+
+    IF seg.deterministic THEN
+        added := FALSE;
+        FOR user_id IN
+            DELETE FROM segments_to_users su
+            WHERE su.segment_slug = target_slug AND su.expiration_date > NOW()
+              AND user_bucket(seg.salt, su.user_id) >= target_percent * 100
+            RETURNING su.user_id
+        LOOP
+            RETURN NEXT;
+        END LOOP;
+
+        IF seg.max_members IS NOT NULL THEN
+            capacity := GREATEST(seg.max_members - (SELECT COUNT(*) FROM segments_to_users su
+                                                    WHERE su.segment_slug = target_slug AND su.expiration_date > NOW()), 0);
+        END IF;
+
+        added := TRUE;
+        FOR user_id IN
+            SELECT id
+            FROM users
+            WHERE user_bucket(seg.salt, id) < target_percent * 100
+              AND id NOT IN (SELECT su.user_id FROM segments_to_users su WHERE su.segment_slug = target_slug)
+              AND (seg.rollout_expires_at IS NULL OR seg.rollout_expires_at > NOW())
+              AND assignment_violation(target_slug, id) IS NULL
+            ORDER BY id
+            LIMIT capacity
+        LOOP
+            INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+            VALUES (target_slug, user_id, COALESCE(seg.rollout_expires_at, 'INFINITY'));
+
+            RETURN NEXT;
+        END LOOP;
+
+        RETURN;
+    END IF;
+
+    target_count := ROUND((SELECT COUNT(*) FROM users) * (target_percent / 100));
+    members_count := (SELECT COUNT(*) FROM segments_to_users su
+                      WHERE su.segment_slug = target_slug AND su.expiration_date > NOW());
+    capacity := GREATEST(seg.max_members - members_count, 0);
+
+    IF members_count > target_count THEN
+        added := FALSE;
+        FOR user_id IN
+            DELETE FROM segments_to_users su
+            WHERE su.segment_slug = target_slug AND su.user_id IN (
+                SELECT m.user_id FROM segments_to_users m
+                WHERE m.segment_slug = target_slug AND m.expiration_date > NOW()
+                ORDER BY m.assigned_at DESC, m.user_id DESC
+                LIMIT members_count - target_count
+            )
+            RETURNING su.user_id
+        LOOP
+            RETURN NEXT;
+        END LOOP;
+    ELSIF members_count < target_count THEN
+        added := TRUE;
+        FOR user_id IN
+            SELECT id
+            FROM users
+            WHERE id NOT IN (SELECT su.user_id FROM segments_to_users su WHERE su.segment_slug = target_slug)
+              AND (seg.rollout_expires_at IS NULL OR seg.rollout_expires_at > NOW())
+              AND assignment_violation(target_slug, id) IS NULL
+            ORDER BY random()
+            LIMIT LEAST(target_count - members_count, capacity)
+        LOOP
+            INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+            VALUES (target_slug, user_id, COALESCE(seg.rollout_expires_at, 'INFINITY'));
+
+            RETURN NEXT;
+        END LOOP;
+    END IF;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
+
+-- Evaluates percentage segments for all users, so the real share gets back to the configured percent.
+-- Segments with max_members are filled only up to their capacity
+CREATE OR REPLACE FUNCTION backfill_rollouts()
+RETURNS TABLE (segment_slug VARCHAR(100), user_id INTEGER) AS
+$$
+DECLARE
+    seg RECORD;
+    users_to_add INTEGER;
+    members_count INTEGER;
+BEGIN
+    FOR seg IN
+        SELECT slug, salt, rollout_percent, deterministic, max_members, rollout_expires_at
+        FROM segments
+        WHERE rollout_percent IS NOT NULL AND (rollout_expires_at IS NULL OR rollout_expires_at > NOW())
+        ORDER BY slug
+    LOOP
+        segment_slug := seg.slug;
+
+        IF seg.max_members IS NOT NULL THEN
+            PERFORM 1 FROM segments WHERE slug = seg.slug FOR NO KEY UPDATE;
+        END IF;
+        members_count := (SELECT COUNT(*) FROM segments_to_users s
+                          WHERE s.segment_slug = seg.slug AND s.expiration_date > NOW());
+
+        IF seg.deterministic THEN
+            users_to_add := NULL;
+        ELSE
+            users_to_add := GREATEST(ROUND((SELECT COUNT(*) FROM users) * (seg.rollout_percent / 100))
+                - members_count, 0);
+        END IF;
+        IF seg.max_members IS NOT NULL THEN
+            users_to_add := LEAST(users_to_add, GREATEST(seg.max_members - members_count, 0));
+        END IF;
+
+        FOR user_id IN
+            SELECT id
+            FROM users
+            WHERE id NOT IN (SELECT s.user_id FROM segments_to_users s WHERE s.segment_slug = seg.slug)
+              AND (NOT seg.deterministic OR user_bucket(seg.salt, id) < seg.rollout_percent * 100)
+              AND assignment_violation(seg.slug, id) IS NULL
+            ORDER BY CASE WHEN seg.deterministic THEN id END, random()
+            LIMIT users_to_add
+        LOOP
+            INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+            VALUES (seg.slug, user_id, COALESCE(seg.rollout_expires_at, 'INFINITY'));
+
+            RETURN NEXT;
+        END LOOP;
+    END LOOP;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
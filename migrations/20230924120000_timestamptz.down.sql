@@ -0,0 +1,74 @@
+-- Values are converted back to the wall-clock time of the same zone as on the way up
+CREATE OR REPLACE FUNCTION pg_temp.legacy_timezone() RETURNS TEXT AS
+$$
+    SELECT COALESCE(NULLIF(current_setting('app.legacy_timezone', true), ''), current_setting('TimeZone'));
+$$
+LANGUAGE SQL;
+
+ALTER TABLE users
+    ALTER COLUMN created_at TYPE TIMESTAMP WITHOUT TIME ZONE USING created_at AT TIME ZONE pg_temp.legacy_timezone();
+ALTER TABLE segments
+    ALTER COLUMN created_at TYPE TIMESTAMP WITHOUT TIME ZONE USING created_at AT TIME ZONE pg_temp.legacy_timezone(),
+    ALTER COLUMN updated_at TYPE TIMESTAMP WITHOUT TIME ZONE USING updated_at AT TIME ZONE pg_temp.legacy_timezone(),
+    ALTER COLUMN archived_at TYPE TIMESTAMP WITHOUT TIME ZONE USING archived_at AT TIME ZONE pg_temp.legacy_timezone(),
+    ALTER COLUMN starts_at TYPE TIMESTAMP WITHOUT TIME ZONE USING starts_at AT TIME ZONE pg_temp.legacy_timezone(),
+    ALTER COLUMN ends_at TYPE TIMESTAMP WITHOUT TIME ZONE USING ends_at AT TIME ZONE pg_temp.legacy_timezone(),
+    ALTER COLUMN rollout_expires_at TYPE TIMESTAMP WITHOUT TIME ZONE USING rollout_expires_at AT TIME ZONE pg_temp.legacy_timezone();
+ALTER TABLE segments_to_users
+    ALTER COLUMN expiration_date TYPE TIMESTAMP WITHOUT TIME ZONE USING expiration_date AT TIME ZONE pg_temp.legacy_timezone(),
+    ALTER COLUMN assigned_at TYPE TIMESTAMP WITHOUT TIME ZONE USING assigned_at AT TIME ZONE pg_temp.legacy_timezone();
+ALTER TABLE segment_user_operations
+    ALTER COLUMN operation_date TYPE TIMESTAMP WITHOUT TIME ZONE USING operation_date AT TIME ZONE pg_temp.legacy_timezone();
+ALTER TABLE segment_changes
+    ALTER COLUMN change_date TYPE TIMESTAMP WITHOUT TIME ZONE USING change_date AT TIME ZONE pg_temp.legacy_timezone();
+ALTER TABLE rollout_steps
+    ALTER COLUMN apply_at TYPE TIMESTAMP WITHOUT TIME ZONE USING apply_at AT TIME ZONE pg_temp.legacy_timezone(),
+    ALTER COLUMN applied_at TYPE TIMESTAMP WITHOUT TIME ZONE USING applied_at AT TIME ZONE pg_temp.legacy_timezone();
+
+DROP FUNCTION IF EXISTS add_random_users(VARCHAR(100), DECIMAL, TIMESTAMP WITH TIME ZONE);
+
+-- Function for adding a share of the users that are not in the segment yet, with an optional expiration.
+-- The number of users is rounded like on segment creation, users the segment can not be assigned to
+-- are skipped and the segment is filled only up to max_members. Returns the added users
+CREATE OR REPLACE FUNCTION add_random_users(target_slug VARCHAR(100), target_percent DECIMAL,
+    expires TIMESTAMP WITHOUT TIME ZONE)
+RETURNS TABLE (user_id INTEGER) AS
+$$
+DECLARE
+    seg RECORD;
+    users_to_add INTEGER;
+BEGIN
+    SELECT slug, max_members INTO seg FROM segments WHERE slug = target_slug FOR UPDATE;
+    IF NOT FOUND THEN
+        RETURN;
+    END IF;
+
+    users_to_add := ROUND((SELECT COUNT(*) FROM users u
+                           WHERE NOT EXISTS (SELECT 1 FROM segments_to_users su
+                                             WHERE su.segment_slug = target_slug AND su.user_id = u.id))
+                          * (target_percent / 100));
+    IF seg.max_members IS NOT NULL THEN
+        users_to_add := LEAST(users_to_add, GREATEST(seg.max_members
+            - (SELECT COUNT(*) FROM segments_to_users su
+               WHERE su.segment_slug = target_slug AND su.expiration_date > NOW()), 0));
+    END IF;
+
+    FOR user_id IN
+        SELECT u.id
+        FROM users u
+        WHERE NOT EXISTS (SELECT 1 FROM segments_to_users su
+                          WHERE su.segment_slug = target_slug AND su.user_id = u.id)
+          AND assignment_violation(target_slug, u.id) IS NULL
+        ORDER BY random()
+        LIMIT users_to_add
+    LOOP
+        INSERT INTO segments_to_users (segment_slug, user_id, expiration_date)
+        VALUES (target_slug, user_id, COALESCE(expires, 'INFINITY'));
+
+        RETURN NEXT;
+    END LOOP;
+
+    RETURN;
+END;
+$$
+LANGUAGE PLPGSQL;
@@ -0,0 +1,26 @@
+DROP TRIGGER IF EXISTS segments_to_users_audit_trigger ON segments_to_users;
+
+-- Trigger for populating the segment_user_operations table
+CREATE OR REPLACE FUNCTION audit_segment_user_operations() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO segment_user_operations (user_id, segment_slug, isAdded, operation_date)
+        VALUES (NEW.user_id, NEW.segment_slug, TRUE, CURRENT_TIMESTAMP);
+        RETURN NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        INSERT INTO segment_user_operations (user_id, segment_slug, isAdded, operation_date)
+        VALUES (OLD.user_id, OLD.segment_slug, FALSE, CURRENT_TIMESTAMP);
+        RETURN OLD;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER segments_to_users_audit_trigger
+AFTER INSERT OR DELETE ON segments_to_users
+FOR EACH ROW
+EXECUTE FUNCTION audit_segment_user_operations();
+
+ALTER TABLE segment_user_operations
+    DROP COLUMN IF EXISTS operation,
+    DROP COLUMN IF EXISTS expiration_date;
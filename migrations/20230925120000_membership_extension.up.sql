@@ -0,0 +1,38 @@
+-- Kind of the operation: added, removed or extended, and the expiration of the membership after it
+ALTER TABLE segment_user_operations
+    ADD COLUMN IF NOT EXISTS operation VARCHAR(10),
+    ADD COLUMN IF NOT EXISTS expiration_date TIMESTAMP WITH TIME ZONE;
+
+UPDATE segment_user_operations SET operation = CASE WHEN isAdded THEN 'added' ELSE 'removed' END;
+
+ALTER TABLE segment_user_operations ALTER COLUMN operation SET NOT NULL;
+
+-- Trigger for populating the segment_user_operations table.
+-- Changing the expiration of a membership is recorded as extended, isAdded stays true for it
+CREATE OR REPLACE FUNCTION audit_segment_user_operations() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO segment_user_operations (user_id, segment_slug, isAdded, operation, expiration_date, operation_date)
+        VALUES (NEW.user_id, NEW.segment_slug, TRUE, 'added', NEW.expiration_date, CURRENT_TIMESTAMP);
+        RETURN NEW;
+    ELSIF TG_OP = 'UPDATE' THEN
+        IF NEW.expiration_date IS DISTINCT FROM OLD.expiration_date THEN
+            INSERT INTO segment_user_operations (user_id, segment_slug, isAdded, operation, expiration_date, operation_date)
+            VALUES (NEW.user_id, NEW.segment_slug, TRUE, 'extended', NEW.expiration_date, CURRENT_TIMESTAMP);
+        END IF;
+        RETURN NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        INSERT INTO segment_user_operations (user_id, segment_slug, isAdded, operation, expiration_date, operation_date)
+        VALUES (OLD.user_id, OLD.segment_slug, FALSE, 'removed', OLD.expiration_date, CURRENT_TIMESTAMP);
+        RETURN OLD;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS segments_to_users_audit_trigger ON segments_to_users;
+
+CREATE TRIGGER segments_to_users_audit_trigger
+AFTER INSERT OR UPDATE OF expiration_date OR DELETE ON segments_to_users
+FOR EACH ROW
+EXECUTE FUNCTION audit_segment_user_operations();
@@ -0,0 +1,10 @@
+-- Memberships already notified about their expiration. The expiration is a part of the key,
+-- so a prolonged membership is notified again before the new expiration
+CREATE TABLE IF NOT EXISTS expiry_notifications (
+    segment_slug VARCHAR(100) NOT NULL,
+    user_id INT NOT NULL,
+    expiration_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    notified_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (segment_slug, user_id, expiration_date),
+    FOREIGN KEY (segment_slug, user_id) REFERENCES segments_to_users (segment_slug, user_id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS segment_user_operations_operation_date_idx;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS segment_user_operations_operation_date_idx ON segment_user_operations (operation_date);
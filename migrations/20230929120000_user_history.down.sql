@@ -0,0 +1 @@
+DROP INDEX IF EXISTS segment_user_operations_user_id_idx;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS segment_user_operations_user_id_idx ON segment_user_operations (user_id, operation_id);
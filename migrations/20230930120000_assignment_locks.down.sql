@@ -0,0 +1,72 @@
+-- Returns the SQLSTATE of the rule the assignment violates or NULL if the user can be assigned.
+-- EX001 - the user is already in another variant of the same experiment
+-- EX002 - the user is already in another segment of the same layer
+-- EX003 - the segment is archived
+-- EX004 - the user is in the holdout and the segment does not allow it
+-- EX005 - the user is not an active member of a prerequisite of the segment
+-- EX006 - the segment already has max_members active members.
+-- The capacity is checked under the segment row lock held until the end of the transaction,
+-- so concurrent assignments to a limited segment are serialized
+CREATE OR REPLACE FUNCTION assignment_violation(assigned_slug VARCHAR(100), assigned_user_id INTEGER)
+RETURNS TEXT AS
+$$
+DECLARE
+    seg RECORD;
+BEGIN
+    IF EXISTS (SELECT 1 FROM segments_to_users
+               WHERE segment_slug = assigned_slug AND user_id = assigned_user_id) THEN
+        RETURN NULL;
+    END IF;
+
+    SELECT slug, experiment_slug, layer, archived_at, allow_holdout, max_members INTO seg FROM segments WHERE slug = assigned_slug;
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    IF seg.archived_at IS NOT NULL THEN
+        RETURN 'EX003';
+    END IF;
+
+    IF NOT seg.allow_holdout AND in_holdout(assigned_user_id) THEN
+        RETURN 'EX004';
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM segment_prerequisites sp
+        WHERE sp.segment_slug = assigned_slug AND NOT EXISTS (
+            SELECT 1 FROM segments_to_users su
+            WHERE su.segment_slug = sp.prerequisite_slug AND su.user_id = assigned_user_id
+              AND su.expiration_date > NOW()
+        )
+    ) THEN
+        RETURN 'EX005';
+    END IF;
+
+    IF seg.experiment_slug IS NOT NULL AND EXISTS (
+        SELECT 1 FROM segments_to_users su
+        JOIN segments s ON s.slug = su.segment_slug
+        WHERE su.user_id = assigned_user_id AND s.experiment_slug = seg.experiment_slug
+    ) THEN
+        RETURN 'EX001';
+    END IF;
+
+    IF seg.layer IS NOT NULL AND EXISTS (
+        SELECT 1 FROM segments_to_users su
+        JOIN segments s ON s.slug = su.segment_slug
+        WHERE su.user_id = assigned_user_id AND s.layer = seg.layer AND su.expiration_date > NOW()
+    ) THEN
+        RETURN 'EX002';
+    END IF;
+
+    IF seg.max_members IS NOT NULL THEN
+        PERFORM 1 FROM segments WHERE slug = assigned_slug FOR NO KEY UPDATE;
+        IF (SELECT COUNT(*) FROM segments_to_users su
+            WHERE su.segment_slug = assigned_slug AND su.expiration_date > NOW()) >= seg.max_members THEN
+            RETURN 'EX006';
+        END IF;
+    END IF;
+
+    RETURN NULL;
+END;
+$$
+LANGUAGE PLPGSQL;
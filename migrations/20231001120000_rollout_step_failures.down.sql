@@ -0,0 +1,4 @@
+ALTER TABLE rollout_steps
+    DROP COLUMN IF EXISTS failed_at,
+    DROP COLUMN IF EXISTS last_error,
+    DROP COLUMN IF EXISTS attempts;
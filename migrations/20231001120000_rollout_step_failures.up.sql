@@ -0,0 +1,6 @@
+-- Failed attempts to apply a step. A step that failed is skipped until the next scheduler run,
+-- so it does not block the due steps of other segments
+ALTER TABLE rollout_steps
+    ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS last_error TEXT,
+    ADD COLUMN IF NOT EXISTS failed_at TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS segments_to_users_removal_policy_trigger ON segments_to_users;
+DROP FUNCTION IF EXISTS enforce_removal_policy();
@@ -0,0 +1,54 @@
+-- Enforces the removal policy of dependent segments on every change of a prerequisite membership,
+-- so manual removals, rollout decreases, the expiry reaper and shortened expirations behave the same.
+-- Removing an active prerequisite membership removes the dependent memberships of the user with the cascade
+-- policy and fails with EX007 for the block policy. Removing an expired one removes the dependent
+-- memberships with any policy, the user has already lost the prerequisite.
+-- Shortening the expiration of a prerequisite membership caps the expiration of the cascade dependents
+-- and fails with EX007 for the block dependents that would outlive it.
+-- Memberships removed by purging the prerequisite segment are skipped, its prerequisite links are removed too
+CREATE OR REPLACE FUNCTION enforce_removal_policy() RETURNS TRIGGER AS $$
+DECLARE
+    dependent RECORD;
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.expiration_date >= OLD.expiration_date THEN
+        RETURN NULL;
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM segments WHERE slug = OLD.segment_slug) THEN
+        RETURN NULL;
+    END IF;
+
+    FOR dependent IN
+        SELECT su.segment_slug, su.expiration_date, s.removal_policy
+        FROM segment_prerequisites sp
+        JOIN segments s ON s.slug = sp.segment_slug
+        JOIN segments_to_users su ON su.segment_slug = sp.segment_slug AND su.user_id = OLD.user_id
+        WHERE sp.prerequisite_slug = OLD.segment_slug
+    LOOP
+        IF TG_OP = 'DELETE' THEN
+            IF dependent.removal_policy = 'block' AND OLD.expiration_date > NOW() THEN
+                RAISE EXCEPTION 'segment % of user % requires segment %',
+                    dependent.segment_slug, OLD.user_id, OLD.segment_slug USING ERRCODE = 'EX007';
+            END IF;
+
+            DELETE FROM segments_to_users
+            WHERE segment_slug = dependent.segment_slug AND user_id = OLD.user_id;
+        ELSIF dependent.expiration_date > NEW.expiration_date THEN
+            IF dependent.removal_policy = 'block' THEN
+                RAISE EXCEPTION 'segment % of user % requires segment %',
+                    dependent.segment_slug, OLD.user_id, OLD.segment_slug USING ERRCODE = 'EX007';
+            END IF;
+
+            UPDATE segments_to_users SET expiration_date = NEW.expiration_date
+            WHERE segment_slug = dependent.segment_slug AND user_id = OLD.user_id;
+        END IF;
+    END LOOP;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER segments_to_users_removal_policy_trigger
+AFTER DELETE OR UPDATE OF expiration_date ON segments_to_users
+FOR EACH ROW
+EXECUTE FUNCTION enforce_removal_policy();
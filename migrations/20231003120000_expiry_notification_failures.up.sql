@@ -0,0 +1,12 @@
+-- Failed deliveries of expiry notifications. A failed membership is skipped until the next run,
+-- so it does not block the others, and is not retried after the maximum number of attempts
+CREATE TABLE IF NOT EXISTS expiry_notification_failures (
+    segment_slug VARCHAR(100) NOT NULL,
+    user_id INT NOT NULL,
+    expiration_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    attempts INT NOT NULL DEFAULT 1,
+    last_error TEXT NOT NULL,
+    failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (segment_slug, user_id, expiration_date),
+    FOREIGN KEY (segment_slug, user_id) REFERENCES segments_to_users (segment_slug, user_id) ON DELETE CASCADE
+);
@@ -0,0 +1,116 @@
+-- Returns the SQLSTATE of the rule assigning the user to the segment violates or NULL, see assignment_violation.
+-- The membership of the user in the segment itself is not taken into account,
+-- so an expired membership can be checked before it is revived in place
+CREATE OR REPLACE FUNCTION assignment_rule_violation(assigned_slug VARCHAR(100), assigned_user_id INTEGER)
+RETURNS TEXT AS
+$$
+DECLARE
+    seg RECORD;
+BEGIN
+    SELECT slug, experiment_slug, layer, archived_at, allow_holdout, max_members INTO seg FROM segments WHERE slug = assigned_slug;
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    IF seg.archived_at IS NOT NULL THEN
+        RETURN 'EX003';
+    END IF;
+
+    IF NOT seg.allow_holdout AND in_holdout(assigned_user_id) THEN
+        RETURN 'EX004';
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM segment_prerequisites sp
+        WHERE sp.segment_slug = assigned_slug AND NOT EXISTS (
+            SELECT 1 FROM segments_to_users su
+            WHERE su.segment_slug = sp.prerequisite_slug AND su.user_id = assigned_user_id
+              AND su.expiration_date > NOW()
+        )
+    ) THEN
+        RETURN 'EX005';
+    END IF;
+
+    IF seg.experiment_slug IS NOT NULL OR seg.layer IS NOT NULL THEN
+        PERFORM 1 FROM users WHERE id = assigned_user_id FOR NO KEY UPDATE;
+    END IF;
+
+    IF seg.experiment_slug IS NOT NULL AND EXISTS (
+        SELECT 1 FROM segments_to_users su
+        JOIN segments s ON s.slug = su.segment_slug
+        WHERE su.user_id = assigned_user_id AND s.experiment_slug = seg.experiment_slug
+          AND su.segment_slug <> assigned_slug
+    ) THEN
+        RETURN 'EX001';
+    END IF;
+
+    IF seg.layer IS NOT NULL AND EXISTS (
+        SELECT 1 FROM segments_to_users su
+        JOIN segments s ON s.slug = su.segment_slug
+        WHERE su.user_id = assigned_user_id AND s.layer = seg.layer AND su.expiration_date > NOW()
+          AND su.segment_slug <> assigned_slug
+    ) THEN
+        RETURN 'EX002';
+    END IF;
+
+    IF seg.max_members IS NOT NULL THEN
+        PERFORM 1 FROM segments WHERE slug = assigned_slug FOR NO KEY UPDATE;
+        IF (SELECT COUNT(*) FROM segments_to_users su
+            WHERE su.segment_slug = assigned_slug AND su.expiration_date > NOW()
+              AND su.user_id <> assigned_user_id) >= seg.max_members THEN
+            RETURN 'EX006';
+        END IF;
+    END IF;
+
+    RETURN NULL;
+END;
+$$
+LANGUAGE PLPGSQL;
+
+-- Returns the SQLSTATE of the rule the assignment violates or NULL if the user can be assigned.
+-- EX001 - the user is already in another variant of the same experiment
+-- EX002 - the user is already in another segment of the same layer
+-- EX003 - the segment is archived
+-- EX004 - the user is in the holdout and the segment does not allow it
+-- EX005 - the user is not an active member of a prerequisite of the segment
+-- EX006 - the segment already has max_members active members.
+-- The capacity is checked under the segment row lock held until the end of the transaction,
+-- so concurrent assignments to a limited segment are serialized.
+-- The variant and layer checks are done under the user row lock, so concurrent assignments
+-- of the same user to segments of one experiment or layer cannot both pass them
+CREATE OR REPLACE FUNCTION assignment_violation(assigned_slug VARCHAR(100), assigned_user_id INTEGER)
+RETURNS TEXT AS
+$$
+BEGIN
+    IF EXISTS (SELECT 1 FROM segments_to_users
+               WHERE segment_slug = assigned_slug AND user_id = assigned_user_id) THEN
+        RETURN NULL;
+    END IF;
+
+    RETURN assignment_rule_violation(assigned_slug, assigned_user_id);
+END;
+$$
+LANGUAGE PLPGSQL;
+
+-- Extending an expired membership revives it in place: the assignment rules are checked as for a new
+-- membership and it counts as assigned now. The row is not removed, so the removal policy of the segments
+-- depending on it is not triggered
+CREATE OR REPLACE FUNCTION guard_membership_revival() RETURNS TRIGGER AS $$
+DECLARE
+    violation TEXT;
+BEGIN
+    violation := assignment_rule_violation(NEW.segment_slug, NEW.user_id);
+    IF violation IS NOT NULL THEN
+        RAISE EXCEPTION 'user % can not be assigned segment %', NEW.user_id, NEW.segment_slug
+            USING ERRCODE = violation;
+    END IF;
+    NEW.assigned_at := NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER segments_to_users_revival_guard_trigger
+BEFORE UPDATE OF expiration_date ON segments_to_users
+FOR EACH ROW
+WHEN (OLD.expiration_date <= NOW() AND NEW.expiration_date > NOW())
+EXECUTE FUNCTION guard_membership_revival();
@@ -0,0 +1 @@
+-- The backfilled expirations are kept, they are the expirations the memberships were added with
@@ -0,0 +1,12 @@
+-- Added operations recorded before 20230925120000_membership_extension have no expiration.
+-- If such an operation is still the last one of the user in the segment, the membership it added exists
+-- and its expiration has not changed since, so the operation gets the expiration of the membership.
+-- Earlier added operations and those of memberships removed or extended since keep no expiration,
+-- whether they had expired at a given time is unknown
+UPDATE segment_user_operations o
+SET expiration_date = su.expiration_date
+FROM segments_to_users su
+WHERE o.operation = 'added' AND o.expiration_date IS NULL
+  AND su.segment_slug = o.segment_slug AND su.user_id = o.user_id
+  AND o.operation_id = (SELECT MAX(l.operation_id) FROM segment_user_operations l
+                        WHERE l.segment_slug = o.segment_slug AND l.user_id = o.user_id);
@@ -0,0 +1,27 @@
+DROP FUNCTION IF EXISTS create_segment_and_add_users(VARCHAR, DECIMAL, BOOLEAN, VARCHAR, VARCHAR, TEXT, VARCHAR, TEXT[]);
+
+-- Auto-assigned segments are inserted with their settings first and then filled by set_segment_rollout,
+-- so every segment column is known before users are picked. The function is kept for existing callers
+-- and delegates to set_segment_rollout, so holdout users are skipped as well.
+-- The memberships expire at segment_expires_at, users enrolled later get it too until it passes
+CREATE OR REPLACE FUNCTION create_segment_and_add_users(new_slug VARCHAR(100), target_percent DECIMAL,
+    deterministic BOOLEAN, segment_salt VARCHAR(100), segment_layer VARCHAR(100),
+    segment_description TEXT, segment_owner VARCHAR(100), segment_tags TEXT[],
+    segment_expires_at TIMESTAMP WITH TIME ZONE DEFAULT NULL)
+RETURNS TABLE (user_id INTEGER, segment_created BOOLEAN) AS
+$$
+BEGIN
+    IF EXISTS (SELECT 1 FROM segments WHERE slug = new_slug) THEN
+        RETURN QUERY SELECT -1, FALSE;
+        RETURN;
+    END IF;
+
+    INSERT INTO segments (slug, salt, rollout_percent, deterministic, layer, description, owner, tags,
+        rollout_expires_at)
+    VALUES (new_slug, segment_salt, target_percent, create_segment_and_add_users.deterministic, segment_layer,
+        segment_description, segment_owner, segment_tags, segment_expires_at);
+
+    RETURN QUERY SELECT r.user_id, TRUE FROM set_segment_rollout(new_slug, target_percent) r WHERE r.added;
+END;
+$$
+LANGUAGE PLPGSQL;
@@ -0,0 +1,30 @@
+UPDATE segment_user_operations SET operation = 'extended' WHERE operation = 'shortened';
+
+-- Trigger for populating the segment_user_operations table.
+-- Changing the expiration of a membership is recorded as extended, isAdded stays true for it.
+-- Deleting an already expired membership is recorded as expired at the time it expired
+CREATE OR REPLACE FUNCTION audit_segment_user_operations() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO segment_user_operations (user_id, segment_slug, isAdded, operation, expiration_date, operation_date)
+        VALUES (NEW.user_id, NEW.segment_slug, TRUE, 'added', NEW.expiration_date, CURRENT_TIMESTAMP);
+        RETURN NEW;
+    ELSIF TG_OP = 'UPDATE' THEN
+        IF NEW.expiration_date IS DISTINCT FROM OLD.expiration_date THEN
+            INSERT INTO segment_user_operations (user_id, segment_slug, isAdded, operation, expiration_date, operation_date)
+            VALUES (NEW.user_id, NEW.segment_slug, TRUE, 'extended', NEW.expiration_date, CURRENT_TIMESTAMP);
+        END IF;
+        RETURN NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        IF OLD.expiration_date <= CURRENT_TIMESTAMP THEN
+            INSERT INTO segment_user_operations (user_id, segment_slug, isAdded, operation, expiration_date, operation_date)
+            VALUES (OLD.user_id, OLD.segment_slug, FALSE, 'expired', OLD.expiration_date, OLD.expiration_date);
+        ELSE
+            INSERT INTO segment_user_operations (user_id, segment_slug, isAdded, operation, expiration_date, operation_date)
+            VALUES (OLD.user_id, OLD.segment_slug, FALSE, 'removed', OLD.expiration_date, CURRENT_TIMESTAMP);
+        END IF;
+        RETURN OLD;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
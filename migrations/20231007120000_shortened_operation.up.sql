@@ -0,0 +1,32 @@
+-- Trigger for populating the segment_user_operations table.
+-- A later expiration of a membership is recorded as extended and an earlier one as shortened,
+-- isAdded stays true for both.
+-- Deleting an already expired membership is recorded as expired at the time it expired
+CREATE OR REPLACE FUNCTION audit_segment_user_operations() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO segment_user_operations (user_id, segment_slug, isAdded, operation, expiration_date, operation_date)
+        VALUES (NEW.user_id, NEW.segment_slug, TRUE, 'added', NEW.expiration_date, CURRENT_TIMESTAMP);
+        RETURN NEW;
+    ELSIF TG_OP = 'UPDATE' THEN
+        IF NEW.expiration_date > OLD.expiration_date THEN
+            INSERT INTO segment_user_operations (user_id, segment_slug, isAdded, operation, expiration_date, operation_date)
+            VALUES (NEW.user_id, NEW.segment_slug, TRUE, 'extended', NEW.expiration_date, CURRENT_TIMESTAMP);
+        ELSIF NEW.expiration_date < OLD.expiration_date THEN
+            INSERT INTO segment_user_operations (user_id, segment_slug, isAdded, operation, expiration_date, operation_date)
+            VALUES (NEW.user_id, NEW.segment_slug, TRUE, 'shortened', NEW.expiration_date, CURRENT_TIMESTAMP);
+        END IF;
+        RETURN NEW;
+    ELSIF TG_OP = 'DELETE' THEN
+        IF OLD.expiration_date <= CURRENT_TIMESTAMP THEN
+            INSERT INTO segment_user_operations (user_id, segment_slug, isAdded, operation, expiration_date, operation_date)
+            VALUES (OLD.user_id, OLD.segment_slug, FALSE, 'expired', OLD.expiration_date, OLD.expiration_date);
+        ELSE
+            INSERT INTO segment_user_operations (user_id, segment_slug, isAdded, operation, expiration_date, operation_date)
+            VALUES (OLD.user_id, OLD.segment_slug, FALSE, 'removed', OLD.expiration_date, CURRENT_TIMESTAMP);
+        END IF;
+        RETURN OLD;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
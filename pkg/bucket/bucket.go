@@ -0,0 +1,23 @@
+package bucket
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"strconv"
+)
+
+// Count is the number of buckets users are spread over, so a bucket equals 0.01%
+const Count = 10000
+
+// Returns the bucket of the user in [0, Count).
+// Must be kept in sync with the user_bucket SQL function: md5(salt || ':' || user_id),
+// first 4 bytes as a big-endian unsigned integer, modulo Count
+func Of(salt string, userID int) int {
+	sum := md5.Sum([]byte(salt + ":" + strconv.Itoa(userID)))
+	return int(binary.BigEndian.Uint32(sum[:4]) % Count)
+}
+
+// Reports whether the user falls into the first percent of buckets
+func InPercent(salt string, userID int, percent int) bool {
+	return Of(salt, userID) < percent*Count/100
+}
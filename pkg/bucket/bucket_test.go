@@ -0,0 +1,29 @@
+package bucket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOf(t *testing.T) {
+	for id := 1; id <= 1000; id++ {
+		b := Of("SEGMENT", id)
+		require.GreaterOrEqual(t, b, 0)
+		require.Less(t, b, Count)
+		require.Equal(t, b, Of("SEGMENT", id))
+	}
+}
+
+func TestInPercent(t *testing.T) {
+	users := 10000
+	assigned := 0
+	for id := 1; id <= users; id++ {
+		if InPercent("SEGMENT", id, 30) {
+			assigned++
+		}
+		require.False(t, InPercent("SEGMENT", id, 0))
+		require.True(t, InPercent("SEGMENT", id, 100))
+	}
+	require.InDelta(t, 3000, assigned, 200)
+}
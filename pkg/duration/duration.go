@@ -0,0 +1,91 @@
+// Package duration parses ISO 8601 durations such as "P1DT12H", "PT30M" or "P2W".
+//
+// Only weeks, days, hours, minutes and seconds are supported: years and months
+// have no fixed length, so they are rejected. A day is always 24 hours
+package duration
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrSyntax = errors.New("duration: invalid ISO 8601 duration")
+
+var dateUnits = map[byte]time.Duration{
+	'W': 7 * 24 * time.Hour,
+	'D': 24 * time.Hour,
+}
+
+var timeUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+}
+
+// Parses the ISO 8601 duration, designators must go in descending order and only seconds may be fractional
+func Parse(s string) (time.Duration, error) {
+	rest := strings.TrimPrefix(s, "P")
+	if rest == s || rest == "" {
+		return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+
+	datePart, timePart, hasTime := strings.Cut(rest, "T")
+	if hasTime && timePart == "" {
+		return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+
+	var d time.Duration
+	for _, part := range []struct {
+		value string
+		units map[byte]time.Duration
+		order string
+	}{
+		{datePart, dateUnits, "WD"},
+		{timePart, timeUnits, "HMS"},
+	} {
+		parsed, err := parsePart(part.value, part.units, part.order)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", err, s)
+		}
+		d += parsed
+	}
+
+	return d, nil
+}
+
+// Sums the components of the date or time part, e.g. "1W2D" or "12H30M"
+func parsePart(s string, units map[byte]time.Duration, order string) (time.Duration, error) {
+	var d time.Duration
+	for s != "" {
+		i := strings.IndexFunc(s, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.'
+		})
+		if i <= 0 {
+			return 0, ErrSyntax
+		}
+
+		unit := s[i]
+		pos := strings.IndexByte(order, unit)
+		if pos < 0 {
+			return 0, ErrSyntax
+		}
+		// every next designator must be smaller than the previous one
+		order = order[pos+1:]
+
+		number := s[:i]
+		if strings.Contains(number, ".") && unit != 'S' {
+			return 0, ErrSyntax
+		}
+		value, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return 0, ErrSyntax
+		}
+		d += time.Duration(value * float64(units[unit]))
+
+		s = s[i+1:]
+	}
+	return d, nil
+}
@@ -0,0 +1,45 @@
+package duration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		duration    string
+		expected    time.Duration
+		expectedErr error
+	}{
+		{"PT6H", 6 * time.Hour, nil},
+		{"PT30M", 30 * time.Minute, nil},
+		{"PT1.5S", 1500 * time.Millisecond, nil},
+		{"P1D", 24 * time.Hour, nil},
+		{"P2W", 14 * 24 * time.Hour, nil},
+		{"P1DT12H", 36 * time.Hour, nil},
+		{"P1W1DT1H1M1S", 8*24*time.Hour + time.Hour + time.Minute + time.Second, nil},
+		{"P0D", 0, nil},
+		{"", 0, ErrSyntax},
+		{"P", 0, ErrSyntax},
+		{"PT", 0, ErrSyntax},
+		{"P1DT", 0, ErrSyntax},
+		{"6H", 0, ErrSyntax},
+		{"P1Y", 0, ErrSyntax},
+		{"P1M", 0, ErrSyntax},
+		{"PT1H1H", 0, ErrSyntax},
+		{"PT30M1H", 0, ErrSyntax},
+		{"P1.5D", 0, ErrSyntax},
+		{"PTH", 0, ErrSyntax},
+		{"P-1D", 0, ErrSyntax},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.duration, func(t *testing.T) {
+			d, err := Parse(tc.duration)
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expected, d)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"io"
 	"os"
 
 	"github.com/sirupsen/logrus"
@@ -17,6 +18,10 @@ func New() *Logger {
 	return &Logger{logger}
 }
 
+func (l *Logger) SetOutput(w io.Writer) {
+	l.log.SetOutput(w)
+}
+
 func (l *Logger) Info(args ...interface{}) {
 	l.log.Info(args...)
 }
@@ -0,0 +1,427 @@
+// Package rules implements a small expression language for targeting users by their attributes, e.g.
+//
+//	country in ["RU", "KZ"] and platform == "ios" and not (plan == "free" or age < 18)
+//
+// Supported operators are ==, !=, <, <=, >, >=, in, not in, and, or, not and parentheses.
+// Values are double-quoted strings, numbers and booleans. A missing attribute equals nothing,
+// so only != and not in match it. Strings are compared lexicographically, which allows comparing ISO dates
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var ErrSyntax = errors.New("rules: syntax error")
+
+// Rule is a parsed expression, safe for concurrent use
+type Rule struct {
+	root node
+}
+
+// Parses the expression into a rule
+func Parse(expr string) (*Rule, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, p.errorf("unexpected %q", p.peek().text)
+	}
+
+	return &Rule{root}, nil
+}
+
+// Reports whether the attributes satisfy the rule
+func (r *Rule) Match(attrs map[string]any) bool {
+	return r.root.eval(attrs)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{tokenLBracket, "[", i})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{tokenRBracket, "]", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokenComma, ",", i})
+			i++
+		case strings.ContainsRune("=!<>", c):
+			op := string(c)
+			if i+1 < len(expr) && expr[i+1] == '=' {
+				op += "="
+			}
+			if op == "=" || op == "!" {
+				return nil, fmt.Errorf("%w: unknown operator %q at %d", ErrSyntax, op, i)
+			}
+			tokens = append(tokens, token{tokenOperator, op, i})
+			i += len(op)
+		case c == '"':
+			end := i + 1
+			for ; end < len(expr) && expr[end] != '"'; end++ {
+				if expr[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("%w: unterminated string at %d", ErrSyntax, i)
+			}
+			s, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid string at %d", ErrSyntax, i)
+			}
+			tokens = append(tokens, token{tokenString, s, i})
+			i = end + 1
+		case c == '-' || unicode.IsDigit(c):
+			end := i + 1
+			for ; end < len(expr) && (unicode.IsDigit(rune(expr[end])) || expr[end] == '.'); end++ {
+			}
+			tokens = append(tokens, token{tokenNumber, expr[i:end], i})
+			i = end
+		case c == '_' || unicode.IsLetter(c):
+			end := i + 1
+			for ; end < len(expr) && isIdentRune(rune(expr[end])); end++ {
+			}
+			tokens = append(tokens, token{tokenIdent, expr[i:end], i})
+			i = end
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q at %d", ErrSyntax, c, i)
+		}
+	}
+
+	return append(tokens, token{tokenEOF, "end of rule", len(expr)}), nil
+}
+
+func isIdentRune(c rune) bool {
+	return c == '_' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isKeyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokenIdent && t.text == word
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s at %d", ErrSyntax, fmt.Sprintf(format, args...), p.peek().pos)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.isKeyword("not") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	if p.peek().kind == tokenLParen {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokenRParen {
+			return nil, p.errorf("expected )")
+		}
+		return expr, nil
+	}
+
+	attr := p.next()
+	if attr.kind != tokenIdent || isReserved(attr.text) {
+		return nil, p.errorf("expected attribute name, got %q", attr.text)
+	}
+
+	negate := false
+	if p.isKeyword("not") {
+		p.next()
+		negate = true
+		if !p.isKeyword("in") {
+			return nil, p.errorf("expected in after not")
+		}
+	}
+	if p.isKeyword("in") {
+		p.next()
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		var n node = inNode{attr.text, list}
+		if negate {
+			n = notNode{n}
+		}
+		return n, nil
+	}
+
+	op := p.next()
+	if op.kind != tokenOperator {
+		return nil, p.errorf("expected operator after %q", attr.text)
+	}
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := value.(bool); ok && op.text != "==" && op.text != "!=" {
+		return nil, fmt.Errorf("%w: booleans can only be compared with == and != at %d", ErrSyntax, op.pos)
+	}
+
+	return compareNode{attr.text, op.text, value}, nil
+}
+
+func (p *parser) parseList() ([]any, error) {
+	if p.next().kind != tokenLBracket {
+		return nil, p.errorf("expected [")
+	}
+	var list []any
+	if p.peek().kind == tokenRBracket {
+		p.next()
+		return list, nil
+	}
+	for {
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+
+		switch p.next().kind {
+		case tokenComma:
+		case tokenRBracket:
+			return list, nil
+		default:
+			return nil, p.errorf("expected , or ]")
+		}
+	}
+}
+
+func (p *parser) parseValue() (any, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokenString:
+		return t.text, nil
+	case t.kind == tokenNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid number %q at %d", ErrSyntax, t.text, t.pos)
+		}
+		return f, nil
+	case t.kind == tokenIdent && (t.text == "true" || t.text == "false"):
+		return t.text == "true", nil
+	}
+	return nil, fmt.Errorf("%w: expected value, got %q at %d", ErrSyntax, t.text, t.pos)
+}
+
+func isReserved(word string) bool {
+	switch word {
+	case "and", "or", "not", "in", "true", "false":
+		return true
+	}
+	return false
+}
+
+type node interface {
+	eval(attrs map[string]any) bool
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(attrs map[string]any) bool {
+	return n.left.eval(attrs) || n.right.eval(attrs)
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(attrs map[string]any) bool {
+	return n.left.eval(attrs) && n.right.eval(attrs)
+}
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(attrs map[string]any) bool {
+	return !n.operand.eval(attrs)
+}
+
+type inNode struct {
+	attr string
+	list []any
+}
+
+func (n inNode) eval(attrs map[string]any) bool {
+	for _, v := range n.list {
+		if c, ok := compare(attrs[n.attr], v); ok && c == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type compareNode struct {
+	attr  string
+	op    string
+	value any
+}
+
+func (n compareNode) eval(attrs map[string]any) bool {
+	c, ok := compare(attrs[n.attr], n.value)
+	if !ok {
+		return n.op == "!="
+	}
+	switch n.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// Compares the attribute with the value, ok is false if they have different types
+func compare(attr any, value any) (int, bool) {
+	switch v := value.(type) {
+	case string:
+		a, ok := attr.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(a, v), true
+	case float64:
+		a, ok := toFloat(attr)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case a < v:
+			return -1, true
+		case a > v:
+			return 1, true
+		}
+		return 0, true
+	case bool:
+		a, ok := attr.(bool)
+		if !ok {
+			return 0, false
+		}
+		if a == v {
+			return 0, true
+		}
+		return 1, true
+	}
+	return 0, false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
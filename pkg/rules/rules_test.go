@@ -0,0 +1,68 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	attrs := map[string]any{
+		"country":       "RU",
+		"platform":      "ios",
+		"plan":          "pro",
+		"age":           float64(27),
+		"beta":          true,
+		"registered_at": "2023-08-15T12:00:00Z",
+	}
+
+	testCases := []struct {
+		name     string
+		rule     string
+		expected bool
+	}{
+		{"Equal", `platform == "ios"`, true},
+		{"Not equal", `platform != "ios"`, false},
+		{"In list", `country in ["RU", "KZ"]`, true},
+		{"Not in list", `country not in ["RU", "KZ"]`, false},
+		{"And", `country in ["RU","KZ"] and platform == "ios"`, true},
+		{"Or", `country == "KZ" or plan == "pro"`, true},
+		{"Not", `not (country == "KZ" or plan == "free")`, true},
+		{"Precedence", `country == "KZ" and plan == "free" or beta == true`, true},
+		{"Number", `age >= 18 and age < 30`, true},
+		{"Date", `registered_at >= "2023-08-01" and registered_at < "2023-09-01"`, true},
+		{"Type mismatch", `age == "27"`, false},
+		{"Missing attribute", `city == "Moscow"`, false},
+		{"Missing attribute not equal", `city != "Moscow"`, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := Parse(tc.rule)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, r.Match(attrs))
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	testCases := []string{
+		``,
+		`country`,
+		`country = "RU"`,
+		`country in "RU"`,
+		`country in ["RU"`,
+		`(country == "RU"`,
+		`country == "RU" and`,
+		`country == "RU`,
+		`beta > true`,
+		`and == "RU"`,
+	}
+
+	for _, rule := range testCases {
+		t.Run(rule, func(t *testing.T) {
+			_, err := Parse(rule)
+			require.ErrorIs(t, err, ErrSyntax)
+		})
+	}
+}